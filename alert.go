@@ -0,0 +1,530 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Alert states
+const (
+	AlertStateFiring   = "firing"
+	AlertStateResolved = "resolved"
+)
+
+const (
+	// Alert rule quantity to detect missing station data
+	AlertQuantityNoData = "no_data"
+	// Missing station data check interval
+	alertNoDataCheckInterval = time.Minute
+	// Max number of notifications waiting to be sent
+	alertNotificationQueueSize = 64
+	// Max time to wait for queued notifications to be sent on stop
+	alertStopTimeout = time.Minute
+)
+
+// AlertQuantityList returns alert rule quantities: measurement fields, PM averages,
+// derived values and missing station data
+func AlertQuantityList() []string {
+	q := MeasurementFieldList()
+	for _, f := range []string{FieldPm25, FieldPm10} {
+		for _, a := range []string{FeedAverage10m, FeedAverage1h, FeedAverage24h, FeedAverageNowCast} {
+			q = append(q, f+"_"+a)
+		}
+	}
+	return append(q, "dew_point", "absolute_humidity", "heat_index", "sea_level_pressure", AlertQuantityNoData)
+}
+
+// alertQuantityValue returns station data quantity value or nil if there is no value
+func alertQuantityValue(data *StationData, quantity string) *float64 {
+	var v *float32
+	if ref := MeasurementFieldRef(data.LastMeasurement, quantity); ref != nil {
+		v = *ref
+	} else if p := strings.SplitN(quantity, "_", 2); len(p) == 2 && (p[0] == FieldPm25 || p[0] == FieldPm10) {
+		if av := data.Averages; av != nil {
+			if p[0] == FieldPm25 && av.Pm25 != nil {
+				v = av.Pm25.value(p[1])
+			} else if p[0] == FieldPm10 && av.Pm10 != nil {
+				v = av.Pm10.value(p[1])
+			}
+		}
+	} else if dv := data.Derived; dv != nil {
+		switch quantity {
+		case "dew_point":
+			v = dv.DewPoint
+		case "absolute_humidity":
+			v = dv.AbsoluteHumidity
+		case "heat_index":
+			v = dv.HeatIndex
+		case "sea_level_pressure":
+			v = dv.SeaLevelPressure
+		}
+	}
+	if v == nil {
+		return nil
+	}
+	f := float64(*v)
+	return &f
+}
+
+// ConfigDuration is the duration represented in JSON as duration string (like "10m")
+type ConfigDuration time.Duration
+
+func (cd *ConfigDuration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*cd = ConfigDuration(d)
+	return nil
+}
+
+func (cd ConfigDuration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(cd).String())
+}
+
+// AlertRule fires alert when quantity value is above or below threshold for given duration
+// and resolves it when value returns back beyond threshold by hysteresis,
+// no data rule fires alert when there is no station data for given duration
+type AlertRule struct {
+	Name       string         `json:"name"`
+	Quantity   string         `json:"quantity"`
+	Above      *float64       `json:"above,omitempty"`
+	Below      *float64       `json:"below,omitempty"`
+	Duration   ConfigDuration `json:"duration,omitempty"`
+	Hysteresis float64        `json:"hysteresis,omitempty"`
+	// Comma-separated list of time of day ranges to not send notifications in
+	QuietHours string `json:"quiet_hours,omitempty"`
+	// Names of notifiers to send notifications with (all notifiers if empty)
+	Notifiers []string `json:"notifiers,omitempty"`
+
+	quietHours []TimeRange
+}
+
+// AlertConfig is the alerting configuration
+type AlertConfig struct {
+	Notifiers []NotifierConfig `json:"notifiers"`
+	Rules     []*AlertRule     `json:"rules"`
+}
+
+func (ar *AlertRule) init() error {
+	if ar.Name == "" {
+		return errors.New("rule name is not set")
+	}
+	if !StringInSlice(ar.Quantity, AlertQuantityList()) {
+		return fmt.Errorf("unknown quantity: %s (known quantities: %s)", ar.Quantity,
+			SliceToString(AlertQuantityList()))
+	}
+	if ar.Quantity == AlertQuantityNoData {
+		if ar.Duration <= 0 {
+			return errors.New("no data rule duration is not set")
+		}
+	} else if (ar.Above == nil) == (ar.Below == nil) {
+		return errors.New("either above or below threshold should be set")
+	}
+	if ar.Hysteresis < 0 {
+		return fmt.Errorf("invalid hysteresis: %v", ar.Hysteresis)
+	}
+	var err error
+	if ar.quietHours, err = ParseTimeRanges(ar.QuietHours); err != nil {
+		return fmt.Errorf("invalid quiet hours: %v", err)
+	}
+	return nil
+}
+
+func (ar *AlertRule) threshold() *float64 {
+	if ar.Above != nil {
+		return ar.Above
+	}
+	return ar.Below
+}
+
+// condition checks value violates rule threshold, threshold is shifted by hysteresis if alert is firing
+func (ar *AlertRule) condition(v float64, firing bool) bool {
+	var h float64
+	if firing {
+		h = ar.Hysteresis
+	}
+	if ar.Above != nil {
+		return v > *ar.Above-h
+	}
+	return v < *ar.Below+h
+}
+
+func (ar *AlertRule) quiet(t time.Time) bool {
+	for _, tr := range ar.quietHours {
+		if tr.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// alertState is the alert rule persisted state
+type alertState struct {
+	// Time since rule condition is met (nil if it's not met)
+	Pending *time.Time `json:"pending,omitempty"`
+	// Time since alert is firing (nil if it's not firing)
+	Firing *time.Time `json:"firing,omitempty"`
+	// Firing alert notification is sent
+	Notified bool `json:"notified,omitempty"`
+	// Firing alert notification is queued to be sent
+	sending bool
+}
+
+// alertNotification is the queued alert event notification
+type alertNotification struct {
+	rule  *AlertRule
+	event *AlertEvent
+	// Notified alert firing time (nil for resolved alert notification)
+	firing *time.Time
+}
+
+// Alerter evaluates alert rules on station data and periodically for missing data
+// and sends alert state changes notifications
+type Alerter struct {
+	sync.Mutex
+
+	rules     []*AlertRule
+	notifiers []Notifier
+	stateFile string
+
+	states   map[string]*alertState
+	lastData time.Time
+	tokenId  string
+
+	// Notifications are sent outside of rule evaluation to not block station data publishing
+	notifications chan alertNotification
+	pending       sync.WaitGroup
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// LoadAlertConfig loads alerting configuration from given JSON file
+func LoadAlertConfig(fn string) (*AlertConfig, error) {
+	b, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	var ac AlertConfig
+	if err := json.Unmarshal(b, &ac); err != nil {
+		return nil, fmt.Errorf("can't parse alert config file %s: %v", fn, err)
+	}
+	return &ac, nil
+}
+
+// NewAlerter creates alerter using given configuration with alert states persisted
+// in given file (empty to not persist states)
+func NewAlerter(config *AlertConfig, stateFile string) (*Alerter, error) {
+	a := &Alerter{
+		rules:         config.Rules,
+		stateFile:     stateFile,
+		states:        make(map[string]*alertState),
+		notifications: make(chan alertNotification, alertNotificationQueueSize),
+		stopCh:        make(chan struct{}),
+	}
+	names := make(map[string]bool)
+	for _, nc := range config.Notifiers {
+		n, err := NewNotifier(nc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid notifier: %v", err)
+		}
+		if names[n.Name()] {
+			return nil, fmt.Errorf("duplicate notifier name: %s", n.Name())
+		}
+		names[n.Name()] = true
+		a.notifiers = append(a.notifiers, n)
+	}
+	rules := make(map[string]bool)
+	for _, r := range a.rules {
+		if err := r.init(); err != nil {
+			return nil, fmt.Errorf("invalid alert rule %q: %v", r.Name, err)
+		}
+		if rules[r.Name] {
+			return nil, fmt.Errorf("duplicate alert rule name: %s", r.Name)
+		}
+		rules[r.Name] = true
+		for _, n := range r.Notifiers {
+			if !names[n] {
+				return nil, fmt.Errorf("alert rule %s: unknown notifier: %s", r.Name, n)
+			}
+		}
+	}
+	return a, nil
+}
+
+func (a *Alerter) Start() error {
+	a.Lock()
+	a.loadState()
+	a.lastData = time.Now()
+	a.Unlock()
+
+	a.wg.Add(2)
+	go a.sendNotifications()
+	go func() {
+		defer a.wg.Done()
+		t := time.NewTicker(alertNoDataCheckInterval)
+		defer t.Stop()
+		for {
+			select {
+			case now := <-t.C:
+				a.Lock()
+				a.evaluateNoData(now)
+				a.Unlock()
+			case <-a.stopCh:
+				return
+			}
+		}
+	}()
+
+	log.Printf("started alerting with %d rule(s) and %d notifier(s)", len(a.rules), len(a.notifiers))
+	return nil
+}
+
+func (a *Alerter) Stop() {
+	// Queued notifications aren't persisted, so they are sent before stop
+	sent := make(chan struct{})
+	go func() {
+		a.pending.Wait()
+		close(sent)
+	}()
+	select {
+	case <-sent:
+	case <-time.After(alertStopTimeout):
+		log.Warn("stopping alerting without sending queued notifications")
+	}
+	close(a.stopCh)
+	a.wg.Wait()
+	log.Print("stopped alerting")
+}
+
+func (a *Alerter) Publish(data *StationData) {
+	a.Lock()
+	defer a.Unlock()
+	a.evaluate(data, time.Now())
+}
+
+// evaluate evaluates alert rules on station data received at given time
+func (a *Alerter) evaluate(data *StationData, now time.Time) {
+	a.lastData = now
+	a.tokenId = data.TokenId
+	for _, r := range a.rules {
+		if r.Quantity == AlertQuantityNoData {
+			a.update(r, false, nil, now)
+			continue
+		}
+		v := alertQuantityValue(data, r.Quantity)
+		if v == nil {
+			// Keep alert state if there is no value
+			continue
+		}
+		a.update(r, r.condition(*v, a.state(r).Firing != nil), v, now)
+	}
+}
+
+// evaluateNoData evaluates missing data rules at given time
+func (a *Alerter) evaluateNoData(now time.Time) {
+	for _, r := range a.rules {
+		if r.Quantity == AlertQuantityNoData {
+			a.update(r, now.Sub(a.lastData) >= time.Duration(r.Duration), nil, now)
+		}
+	}
+}
+
+func (a *Alerter) state(r *AlertRule) *alertState {
+	s, ok := a.states[r.Name]
+	if !ok {
+		s = &alertState{}
+		a.states[r.Name] = s
+	}
+	return s
+}
+
+// update updates rule alert state by rule condition and sends notifications on state changes
+func (a *Alerter) update(r *AlertRule, condition bool, v *float64, now time.Time) {
+	s := a.state(r)
+	changed := false
+
+	switch {
+	case s.Firing == nil && condition:
+		if s.Pending == nil {
+			s.Pending = &now
+			changed = true
+		}
+		// Missing data rule duration is already checked by condition
+		if r.Quantity == AlertQuantityNoData || now.Sub(*s.Pending) >= time.Duration(r.Duration) {
+			s.Firing, s.Notified = &now, false
+			changed = true
+			log.Warnf("alert %s is firing", r.Name)
+		}
+	case s.Firing == nil && !condition:
+		if s.Pending != nil {
+			s.Pending = nil
+			changed = true
+		}
+	case s.Firing != nil && !condition:
+		log.Infof("alert %s is resolved", r.Name)
+		if s.Notified || s.sending {
+			a.queue(alertNotification{rule: r, event: a.event(r, AlertStateResolved, v, now)})
+		}
+		*s = alertState{}
+		changed = true
+	}
+
+	// Firing alert notification is sent after quiet hours or retried after failure
+	if s.Firing != nil && !s.Notified && !s.sending && !r.quiet(now) {
+		s.sending = a.queue(alertNotification{rule: r, event: a.event(r, AlertStateFiring, v, now),
+			firing: s.Firing})
+	}
+
+	if changed {
+		a.saveState()
+	}
+}
+
+func (a *Alerter) event(r *AlertRule, state string, v *float64, now time.Time) *AlertEvent {
+	e := &AlertEvent{
+		Rule:      r.Name,
+		State:     state,
+		Quantity:  r.Quantity,
+		Value:     v,
+		Threshold: r.threshold(),
+		TokenId:   a.tokenId,
+		Time:      now,
+	}
+	switch {
+	case r.Quantity == AlertQuantityNoData && state == AlertStateFiring:
+		e.Message = fmt.Sprintf("no station data since %s", a.lastData.Format(time.RFC1123))
+	case r.Quantity == AlertQuantityNoData:
+		e.Message = "station data is received again"
+	default:
+		direction := "above"
+		if r.Below != nil {
+			direction = "below"
+		}
+		if state == AlertStateResolved {
+			direction = "no longer " + direction
+		}
+		value := "unknown"
+		if v != nil {
+			value = fmt.Sprintf("%.1f", *v)
+		}
+		e.Message = fmt.Sprintf("%s is %s, %s threshold %g", r.Quantity, value, direction, *r.threshold())
+	}
+	if a.tokenId != "" {
+		e.Message = fmt.Sprintf("%s (station %s)", e.Message, SubString(a.tokenId, 0, 12))
+	}
+	return e
+}
+
+// queue queues notification to be sent and returns false if notification queue is full
+func (a *Alerter) queue(n alertNotification) bool {
+	a.pending.Add(1)
+	select {
+	case a.notifications <- n:
+		return true
+	default:
+		a.pending.Done()
+		log.Errorf("can't send alert %s notification: notification queue is full", n.rule.Name)
+		return false
+	}
+}
+
+// sendNotifications sends queued notifications until alerter is stopped
+// and marks firing alerts notified if notification is sent
+func (a *Alerter) sendNotifications() {
+	defer a.wg.Done()
+	for {
+		select {
+		case n := <-a.notifications:
+			sent := a.notify(n.rule, n.event)
+			if n.firing != nil {
+				a.Lock()
+				// Alert may be resolved or fired again while notification is sent
+				if s := a.state(n.rule); s.Firing != nil && s.Firing.Equal(*n.firing) {
+					s.sending, s.Notified = false, sent
+					if sent {
+						a.saveState()
+					}
+				}
+				a.Unlock()
+			}
+			a.pending.Done()
+		case <-a.stopCh:
+			return
+		}
+	}
+}
+
+// notify sends event notification with rule notifiers and returns true if any notification is sent
+func (a *Alerter) notify(r *AlertRule, e *AlertEvent) bool {
+	sent := false
+	for _, n := range a.notifiers {
+		if len(r.Notifiers) > 0 && !StringInSlice(n.Name(), r.Notifiers) {
+			continue
+		}
+		if err := n.Notify(e); err != nil {
+			log.Errorf("can't send alert %s notification with %s notifier: %v", r.Name, n.Name(), err)
+			continue
+		}
+		log.Debugf("sent alert %s notification with %s notifier", r.Name, n.Name())
+		sent = true
+	}
+	return sent
+}
+
+func (a *Alerter) loadState() {
+	if a.stateFile == "" {
+		return
+	}
+	b, err := ioutil.ReadFile(a.stateFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Errorf("can't read alert state: %v", err)
+		}
+		return
+	}
+	if err := json.Unmarshal(b, &a.states); err != nil {
+		log.Errorf("can't parse alert state: %v", err)
+	}
+}
+
+func (a *Alerter) saveState() {
+	if a.stateFile == "" {
+		return
+	}
+	b, err := json.Marshal(a.states)
+	if err != nil {
+		log.Errorf("can't marshal alert state: %v", err)
+		return
+	}
+	if err := ioutil.WriteFile(a.stateFile, b, 0644); err != nil {
+		log.Errorf("can't save alert state: %v", err)
+	}
+}
@@ -0,0 +1,268 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openairtech/api"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testNotifier struct {
+	events []*AlertEvent
+	fail   bool
+}
+
+func (tn *testNotifier) Name() string {
+	return "test"
+}
+
+func (tn *testNotifier) Notify(e *AlertEvent) error {
+	if tn.fail {
+		return errors.New("failed")
+	}
+	tn.events = append(tn.events, e)
+	return nil
+}
+
+func TestLoadAlertConfig(t *testing.T) {
+	config, err := LoadAlertConfig("testdata/alerts.json")
+	require.NoError(t, err)
+	a, err := NewAlerter(config, "")
+	require.NoError(t, err)
+	require.Len(t, a.notifiers, 4)
+	require.Equal(t, "phone", a.notifiers[1].Name())
+	require.Equal(t, ConfigDuration(10*time.Minute), a.rules[0].Duration)
+	require.Len(t, a.rules[0].quietHours, 1)
+
+	for _, r := range []*AlertRule{
+		{Name: "r", Quantity: "co2", Above: new(float64)},
+		{Name: "r", Quantity: FieldPm25},
+		{Name: "r", Quantity: AlertQuantityNoData},
+		{Name: "r", Quantity: FieldPm25, Above: new(float64), Notifiers: []string{"unknown"}},
+	} {
+		_, err = NewAlerter(&AlertConfig{Rules: []*AlertRule{r}}, "")
+		require.Error(t, err)
+	}
+}
+
+func TestAlerter(t *testing.T) {
+	threshold := 35.0
+	stateFile := filepath.Join(t.TempDir(), "alerts.json")
+	a, err := NewAlerter(&AlertConfig{Rules: []*AlertRule{
+		{Name: "pm25", Quantity: FieldPm25, Above: &threshold, Duration: ConfigDuration(10 * time.Minute),
+			Hysteresis: 5, QuietHours: "23:00-07:00"},
+		{Name: "offline", Quantity: AlertQuantityNoData, Duration: ConfigDuration(15 * time.Minute)},
+	}}, stateFile)
+	require.NoError(t, err)
+	tn := &testNotifier{}
+	a.notifiers = []Notifier{tn}
+	require.NoError(t, a.Start())
+
+	now := time.Date(2026, 7, 1, 12, 0, 0, 0, time.Local)
+	data := func(pm25 float32) *StationData {
+		return &StationData{TokenId: "0123456789abcdef", LastMeasurement: &api.Measurement{Pm25: &pm25}}
+	}
+	// Rules are evaluated and then queued notifications are waited to be sent
+	evaluate := func(d *StationData, now time.Time) {
+		a.Lock()
+		a.evaluate(d, now)
+		a.Unlock()
+		a.pending.Wait()
+	}
+	evaluateNoData := func(now time.Time) {
+		a.Lock()
+		a.evaluateNoData(now)
+		a.Unlock()
+		a.pending.Wait()
+	}
+
+	evaluate(data(40), now)
+	evaluate(data(40), now.Add(5*time.Minute))
+	require.Empty(t, tn.events)
+	evaluate(data(40), now.Add(10*time.Minute))
+	require.Len(t, tn.events, 1)
+	require.Equal(t, AlertStateFiring, tn.events[0].State)
+	require.Equal(t, "pm25 is 40.0, above threshold 35 (station 0123456789ab)", tn.events[0].Message)
+
+	// No duplicate notification after restart
+	a.Stop()
+	a, err = NewAlerter(&AlertConfig{Rules: a.rules}, stateFile)
+	require.NoError(t, err)
+	a.notifiers = []Notifier{tn}
+	require.NoError(t, a.Start())
+	defer a.Stop()
+	evaluate(data(32), now.Add(11*time.Minute))
+	require.Len(t, tn.events, 1, "value within hysteresis keeps alert firing")
+	evaluate(data(29), now.Add(12*time.Minute))
+	require.Len(t, tn.events, 2)
+	require.Equal(t, AlertStateResolved, tn.events[1].State)
+
+	// Notification is postponed until quiet hours end
+	night := time.Date(2026, 7, 1, 23, 0, 0, 0, time.Local)
+	evaluate(data(50), night)
+	evaluate(data(50), night.Add(time.Hour))
+	require.Len(t, tn.events, 2)
+	evaluate(data(50), night.Add(8*time.Hour))
+	require.Len(t, tn.events, 3)
+
+	// Missing data
+	a.Lock()
+	a.lastData = now
+	a.Unlock()
+	evaluateNoData(now.Add(10 * time.Minute))
+	require.Len(t, tn.events, 3)
+	tn.fail = true
+	evaluateNoData(now.Add(15 * time.Minute))
+	require.Len(t, tn.events, 3)
+	tn.fail = false
+	evaluateNoData(now.Add(16 * time.Minute))
+	require.Len(t, tn.events, 4, "failed notification is retried")
+	require.Equal(t, "offline", tn.events[3].Rule)
+	evaluate(data(20), now.Add(20*time.Minute))
+	require.Equal(t, []string{"pm25", AlertStateResolved}, []string{tn.events[4].Rule, tn.events[4].State})
+	require.Equal(t, []string{"offline", AlertStateResolved}, []string{tn.events[5].Rule, tn.events[5].State})
+}
+
+type blockingNotifier struct {
+	sync.Mutex
+	release chan struct{}
+	states  []string
+}
+
+func (bn *blockingNotifier) Name() string {
+	return "blocking"
+}
+
+func (bn *blockingNotifier) Notify(e *AlertEvent) error {
+	<-bn.release
+	bn.Lock()
+	defer bn.Unlock()
+	bn.states = append(bn.states, e.State)
+	return nil
+}
+
+func TestAlerter_SlowNotifier(t *testing.T) {
+	threshold := 35.0
+	a, err := NewAlerter(&AlertConfig{Rules: []*AlertRule{
+		{Name: "pm25", Quantity: FieldPm25, Above: &threshold},
+	}}, "")
+	require.NoError(t, err)
+	bn := &blockingNotifier{release: make(chan struct{})}
+	a.notifiers = []Notifier{bn}
+	require.NoError(t, a.Start())
+	defer a.Stop()
+
+	// Station data publishing isn't blocked by notification sending
+	pm25 := float32(40)
+	done := make(chan struct{})
+	go func() {
+		a.Publish(&StationData{LastMeasurement: &api.Measurement{Pm25: &pm25}})
+		a.Publish(&StationData{LastMeasurement: &api.Measurement{Pm25: &pm25}})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "station data publishing is blocked")
+	}
+
+	close(bn.release)
+	a.pending.Wait()
+	a.Lock()
+	defer a.Unlock()
+	require.True(t, a.state(a.rules[0]).Notified)
+}
+
+func TestAlerter_StopSendsQueued(t *testing.T) {
+	threshold := 35.0
+	a, err := NewAlerter(&AlertConfig{Rules: []*AlertRule{
+		{Name: "pm25", Quantity: FieldPm25, Above: &threshold},
+	}}, "")
+	require.NoError(t, err)
+	bn := &blockingNotifier{release: make(chan struct{})}
+	a.notifiers = []Notifier{bn}
+	require.NoError(t, a.Start())
+
+	// Alert is fired and resolved while firing notification is being sent
+	pm25, low := float32(40), float32(20)
+	a.Publish(&StationData{LastMeasurement: &api.Measurement{Pm25: &pm25}})
+	a.Publish(&StationData{LastMeasurement: &api.Measurement{Pm25: &low}})
+
+	time.AfterFunc(10*time.Millisecond, func() { close(bn.release) })
+	a.Stop()
+	bn.Lock()
+	defer bn.Unlock()
+	require.Equal(t, []string{AlertStateFiring, AlertStateResolved}, bn.states)
+}
+
+func TestNotifiers(t *testing.T) {
+	var requests []*http.Request
+	var bodies []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r)
+		bodies = append(bodies, string(b))
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer ts.Close()
+	defer func(u string) { telegramApiUrl = u }(telegramApiUrl)
+	telegramApiUrl = ts.URL
+
+	threshold := 35.0
+	e := &AlertEvent{Rule: "pm25", State: AlertStateFiring, Quantity: FieldPm25, Threshold: &threshold,
+		Time: time.Unix(1782900000, 0), Message: "pm25 is 40.0, above threshold 35"}
+
+	for _, c := range []NotifierConfig{
+		{Type: NotifierWebhook, Url: ts.URL + "/hook", Headers: map[string]string{"X-Key": "k"}},
+		{Type: NotifierTelegram, Token: "123:ABC", ChatId: "42"},
+		{Type: NotifierNtfy, Url: ts.URL + "/topic", Token: "tk"},
+	} {
+		n, err := NewNotifier(c)
+		require.NoError(t, err)
+		require.NoError(t, n.Notify(e))
+	}
+
+	require.Len(t, requests, 3)
+	require.Equal(t, "/hook", requests[0].URL.Path)
+	require.Equal(t, "k", requests[0].Header.Get("X-Key"))
+	var we AlertEvent
+	require.NoError(t, json.Unmarshal([]byte(bodies[0]), &we))
+	require.Equal(t, "pm25", we.Rule)
+
+	require.Equal(t, "/bot123:ABC/sendMessage", requests[1].URL.Path)
+	require.JSONEq(t, `{"chat_id":"42","text":"OpenAir station alert firing: pm25\npm25 is 40.0, above threshold 35"}`,
+		bodies[1])
+
+	require.Equal(t, "/topic", requests[2].URL.Path)
+	require.Equal(t, "Bearer tk", requests[2].Header.Get("Authorization"))
+	require.Equal(t, "high", requests[2].Header.Get("Priority"))
+	require.Equal(t, e.Message, bodies[2])
+
+	_, err := NewNotifier(NotifierConfig{Type: NotifierSmtp, Host: "smtp.example.com"})
+	require.Error(t, err)
+	_, err = NewNotifier(NotifierConfig{Type: "pager"})
+	require.Error(t, err)
+}
@@ -0,0 +1,251 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/openairtech/api"
+)
+
+// PM values fed to feeders
+const (
+	FeedAverageNone    = "none"
+	FeedAverage10m     = "10m"
+	FeedAverage1h      = "1h"
+	FeedAverage24h     = "24h"
+	FeedAverageNowCast = "nowcast"
+)
+
+func FeedAverageList() []string {
+	return []string{FeedAverageNone, FeedAverage10m, FeedAverage1h, FeedAverage24h, FeedAverageNowCast}
+}
+
+const (
+	// Longest PM values averaging window
+	pmAveragesMaxWindow = 24 * time.Hour
+	// Min part of averaging window covered by values to calculate average,
+	// no gap between values (or window bounds) may be longer than the rest of the window
+	pmAveragesMinCoverage = 0.75
+	// Number of hourly averages used for NowCast calculation
+	nowCastHours = 12
+	// Min NowCast weight factor for particulate matter
+	nowCastMinWeight = 0.5
+)
+
+// PmAverageValues are the PM value averages, averages without enough values are omitted
+type PmAverageValues struct {
+	Avg10m  *float32 `json:"avg_10m,omitempty"`
+	Avg1h   *float32 `json:"avg_1h,omitempty"`
+	Avg24h  *float32 `json:"avg_24h,omitempty"`
+	NowCast *float32 `json:"nowcast,omitempty"`
+}
+
+func (av *PmAverageValues) value(average string) *float32 {
+	switch average {
+	case FeedAverage10m:
+		return av.Avg10m
+	case FeedAverage1h:
+		return av.Avg1h
+	case FeedAverage24h:
+		return av.Avg24h
+	case FeedAverageNowCast:
+		return av.NowCast
+	}
+	return nil
+}
+
+// PmAveragesStatus are the PM2.5 and PM10 value averages
+type PmAveragesStatus struct {
+	Pm25 *PmAverageValues `json:"pm25,omitempty"`
+	Pm10 *PmAverageValues `json:"pm10,omitempty"`
+}
+
+type pmSample struct {
+	t    time.Time
+	pm25 *float32
+	pm10 *float32
+}
+
+// PmAverages keeps PM values for last 24 hours to calculate rolling averages and EPA NowCast
+type PmAverages struct {
+	feedAverage string
+
+	samples []pmSample
+}
+
+// NewPmAverages creates PM averages with given average to feed instead of instantaneous values
+func NewPmAverages(feedAverage string) (*PmAverages, error) {
+	if !StringInSlice(feedAverage, FeedAverageList()) {
+		return nil, fmt.Errorf("unknown average: %s (known averages: %s)", feedAverage,
+			SliceToString(FeedAverageList()))
+	}
+	return &PmAverages{feedAverage: feedAverage}, nil
+}
+
+// Seed adds PM values of given history records
+func (pa *PmAverages) Seed(records []HistoryRecord) {
+	for i := range records {
+		pa.Add(&records[i].Measurement)
+	}
+}
+
+// Add adds measurement PM values
+func (pa *PmAverages) Add(m *api.Measurement) {
+	if m.Timestamp == nil || (m.Pm25 == nil && m.Pm10 == nil) {
+		return
+	}
+	t := time.Time(*m.Timestamp)
+	if n := len(pa.samples); n > 0 && !t.After(pa.samples[n-1].t) {
+		return
+	}
+	pa.samples = append(pa.samples, pmSample{t: t, pm25: m.Pm25, pm10: m.Pm10})
+
+	// Remove values out of longest averaging window
+	i := 0
+	for i < len(pa.samples) && t.Sub(pa.samples[i].t) >= pmAveragesMaxWindow {
+		i++
+	}
+	pa.samples = pa.samples[i:]
+}
+
+// Status returns PM value averages at given time
+func (pa *PmAverages) Status(now time.Time) *PmAveragesStatus {
+	s := &PmAveragesStatus{
+		Pm25: pa.values(now, func(s *pmSample) *float32 { return s.pm25 }),
+		Pm10: pa.values(now, func(s *pmSample) *float32 { return s.pm10 }),
+	}
+	if s.Pm25 == nil && s.Pm10 == nil {
+		return nil
+	}
+	return s
+}
+
+func (pa *PmAverages) values(now time.Time, value func(s *pmSample) *float32) *PmAverageValues {
+	av := &PmAverageValues{
+		Avg10m:  pa.average(now, 10*time.Minute, value),
+		Avg1h:   pa.average(now, time.Hour, value),
+		Avg24h:  pa.average(now, 24*time.Hour, value),
+		NowCast: pa.nowCast(now, value),
+	}
+	if *av == (PmAverageValues{}) {
+		return nil
+	}
+	return av
+}
+
+// average returns average of values in given window before given time
+// or nil if values don't cover enough of the window
+func (pa *PmAverages) average(now time.Time, window time.Duration, value func(s *pmSample) *float32) *float32 {
+	v, ok := pa.windowAverage(now.Add(-window), now, value)
+	if !ok {
+		return nil
+	}
+	return &v
+}
+
+func (pa *PmAverages) windowAverage(start, end time.Time, value func(s *pmSample) *float32) (float32, bool) {
+	var sum float64
+	var maxGap time.Duration
+	prev := start
+	n := 0
+	for i := range pa.samples {
+		s := &pa.samples[i]
+		if !s.t.After(start) || s.t.After(end) {
+			continue
+		}
+		v := value(s)
+		if v == nil {
+			continue
+		}
+		if gap := s.t.Sub(prev); gap > maxGap {
+			maxGap = gap
+		}
+		prev = s.t
+		sum += float64(*v)
+		n++
+	}
+	if gap := end.Sub(prev); gap > maxGap {
+		maxGap = gap
+	}
+	window := end.Sub(start)
+	if n == 0 || maxGap > time.Duration(float64(window)*(1-pmAveragesMinCoverage)) {
+		return 0, false
+	}
+	return Float32Round(float32(sum/float64(n)), 1), true
+}
+
+// nowCast calculates US EPA NowCast for particulate matter from hourly averages of last 12 hours
+// https://usepa.servicenowservices.com/airnow?id=kb_article_view&sysparm_article=KB0011856
+func (pa *PmAverages) nowCast(now time.Time, value func(s *pmSample) *float32) *float32 {
+	var hourly [nowCastHours]*float64
+	min, max := math.MaxFloat64, 0.0
+	for i := 0; i < nowCastHours; i++ {
+		end := now.Add(-time.Duration(i) * time.Hour)
+		if v, ok := pa.windowAverage(end.Add(-time.Hour), end, value); ok {
+			c := float64(v)
+			hourly[i] = &c
+			min, max = math.Min(min, c), math.Max(max, c)
+		}
+	}
+	// At least two of three most recent hourly averages are required
+	recent := 0
+	for _, c := range hourly[:3] {
+		if c != nil {
+			recent++
+		}
+	}
+	if recent < 2 {
+		return nil
+	}
+	w := nowCastMinWeight
+	if max > 0 {
+		w = math.Max(min/max, nowCastMinWeight)
+	}
+	var sum, weights float64
+	for i, c := range hourly {
+		if c == nil {
+			continue
+		}
+		k := math.Pow(w, float64(i))
+		sum += k * *c
+		weights += k
+	}
+	v := Float32Round(float32(sum/weights), 1)
+	return &v
+}
+
+// FeedData returns station data with PM values replaced by configured averages
+// (values are omitted if there is no average yet) or unchanged data if averages are not fed
+func (pa *PmAverages) FeedData(data *StationData) *StationData {
+	if pa.feedAverage == FeedAverageNone {
+		return data
+	}
+	fd := *data
+	m := *data.LastMeasurement
+	m.Pm25, m.Pm10 = nil, nil
+	if av := data.Averages; av != nil {
+		if av.Pm25 != nil {
+			m.Pm25 = av.Pm25.value(pa.feedAverage)
+		}
+		if av.Pm10 != nil {
+			m.Pm10 = av.Pm10.value(pa.feedAverage)
+		}
+	}
+	fd.LastMeasurement = &m
+	return &fd
+}
@@ -0,0 +1,89 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openairtech/api"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testPm25Measurement(t time.Time, pm25 float32) *api.Measurement {
+	ts := api.UnixTime(t)
+	return &api.Measurement{Timestamp: &ts, Pm25: &pm25}
+}
+
+func TestPmAverages(t *testing.T) {
+	pa, err := NewPmAverages(FeedAverage1h)
+	require.NoError(t, err)
+	_, err = NewPmAverages("2h")
+	require.Error(t, err)
+
+	start := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	add := func(minutes int, pm25 float32) {
+		for i := 0; i < minutes; i++ {
+			now = now.Add(time.Minute)
+			pa.Add(testPm25Measurement(now, pm25))
+		}
+	}
+
+	add(5, 10)
+	require.Nil(t, pa.Status(now), "10m window is not covered enough")
+
+	add(5, 20)
+	s := pa.Status(now)
+	require.Nil(t, s.Pm10)
+	require.Equal(t, float32(15), *s.Pm25.Avg10m)
+	require.Nil(t, s.Pm25.Avg1h)
+	require.Nil(t, s.Pm25.NowCast)
+
+	// Hourly averages: 10 (most recent), 20 (previous hour)
+	add(50, 20)
+	add(60, 10)
+	s = pa.Status(now)
+	require.Equal(t, float32(10), *s.Pm25.Avg1h)
+	require.Nil(t, s.Pm25.Avg24h)
+	// Weight is min/max = 10/19.2: (10 + w * 19.2) / (1 + w)
+	require.Equal(t, float32(13.2), *s.Pm25.NowCast)
+
+	// Missing recent hours disable NowCast
+	s = pa.Status(now.Add(2 * time.Hour))
+	require.Nil(t, s)
+
+	add(24*60, 30)
+	s = pa.Status(now)
+	require.Equal(t, float32(30), *s.Pm25.Avg24h)
+	require.Equal(t, float32(30), *s.Pm25.NowCast)
+	require.Len(t, pa.samples, 24*60)
+
+	data := &StationData{LastMeasurement: testPm25Measurement(now, 42), Averages: s}
+	fd := pa.FeedData(data)
+	require.Equal(t, float32(30), *fd.LastMeasurement.Pm25)
+	require.Equal(t, float32(42), *data.LastMeasurement.Pm25)
+
+	// Long gap between values in the window disables average
+	pa, err = NewPmAverages(FeedAverage24h)
+	require.NoError(t, err)
+	add(60, 20)
+	now = now.Add(20 * time.Hour)
+	add(3*60, 10)
+	s = pa.Status(now)
+	require.Equal(t, float32(10), *s.Pm25.Avg1h)
+	require.Nil(t, s.Pm25.Avg24h)
+}
@@ -0,0 +1,453 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	CommandCalibrate = "calibrate"
+
+	// Min number of aligned station and reference values to fit correction model
+	calibrationMinSamples = 24
+)
+
+// Reference CSV time column names and time layouts
+var (
+	referenceTimeColumns = []string{"time", "timestamp", "datetime", "date"}
+	referenceTimeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02T15:04"}
+)
+
+// calibrationSample is the aligned station and reference values averaged over time window
+type calibrationSample struct {
+	station     float64
+	humidity    float64
+	hasHumidity bool
+	reference   float64
+}
+
+// CalibrationStats is the correction model fit quality statistics
+type CalibrationStats struct {
+	Samples int
+	R2      float64
+	// R² adjusted for number of model regressors
+	AdjustedR2 float64
+	Rmse       float64
+	Bias       float64
+}
+
+// calibrationWindow accumulates values for averaging
+type calibrationWindow struct {
+	sums   map[string]float64
+	counts map[string]int
+}
+
+func (cw *calibrationWindow) add(field string, v *float32) {
+	if v == nil {
+		return
+	}
+	cw.sums[field] += float64(*v)
+	cw.counts[field]++
+}
+
+func (cw *calibrationWindow) mean(field string) (float64, bool) {
+	n := cw.counts[field]
+	if n == 0 {
+		return 0, false
+	}
+	return cw.sums[field] / float64(n), true
+}
+
+// calibrationWindows are the time windows keyed by window start Unix time
+type calibrationWindows map[int64]*calibrationWindow
+
+func (cws calibrationWindows) window(t time.Time, d time.Duration) *calibrationWindow {
+	ts := t.Truncate(d).Unix()
+	cw, ok := cws[ts]
+	if !ok {
+		cw = &calibrationWindow{sums: make(map[string]float64), counts: make(map[string]int)}
+		cws[ts] = cw
+	}
+	return cw
+}
+
+// runCalibrate runs calibrate command with given arguments and returns process exit code
+func runCalibrate(args []string) int {
+	fs := flag.NewFlagSet(CommandCalibrate, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s [options]\n\n"+
+			"Fits station PM correction models against co-located reference station values\n"+
+			"and writes PM correction profile (see -pm-calibration station option).\n\n"+
+			"Reference CSV file must have header with time column (%s) and %s and/or %s columns.\n\n",
+			os.Args[0], CommandCalibrate, SliceToString(referenceTimeColumns), FieldPm25, FieldPm10)
+		fs.PrintDefaults()
+	}
+	historyDir := fs.String("history", "", "station measurements history directory")
+	referenceFile := fs.String("reference", "", "reference station values CSV file")
+	referenceOffset := fs.Duration("reference-offset", 0, "duration to add to reference value times "+
+		"(like -1h if reference values are labeled with averaging period end)")
+	output := fs.String("o", "", "PM correction profile output JSON file (empty to print report only)")
+	window := fs.Duration("window", time.Hour, "values averaging time window")
+	from := fs.String("from", "", "calibration period start date (YYYY-MM-DD)")
+	to := fs.String("to", "", "calibration period end date (YYYY-MM-DD, exclusive)")
+	_ = fs.Parse(args)
+
+	if *historyDir == "" || *referenceFile == "" {
+		fs.Usage()
+		return 2
+	}
+
+	if err := calibrate(os.Stdout, *historyDir, *referenceFile, *referenceOffset, *output, *window,
+		*from, *to); err != nil {
+		fmt.Fprintf(os.Stderr, "calibration failed: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func calibrate(w io.Writer, historyDir, referenceFile string, referenceOffset time.Duration, output string,
+	window time.Duration, from, to string) error {
+	after, before := time.Time{}, time.Now().Add(24*time.Hour)
+	var err error
+	if from != "" {
+		if after, err = time.ParseInLocation("2006-01-02", from, time.Local); err != nil {
+			return fmt.Errorf("invalid period start date: %v", err)
+		}
+		after = after.Add(-time.Nanosecond)
+	}
+	if to != "" {
+		if before, err = time.ParseInLocation("2006-01-02", to, time.Local); err != nil {
+			return fmt.Errorf("invalid period end date: %v", err)
+		}
+	}
+
+	records, err := NewHistory(historyDir, 0).Records(after, before, 0)
+	if err != nil {
+		return fmt.Errorf("can't read station history: %v", err)
+	}
+	station := make(calibrationWindows)
+	corrected := 0
+	for _, r := range records {
+		m := r.RawMeasurement
+		if m == nil {
+			// Records saved before raw values were kept, values could be corrected
+			m = &r.Measurement
+			corrected++
+		}
+		cw := station.window(r.Time(), window)
+		cw.add(FieldPm25, m.Pm25)
+		cw.add(FieldPm10, m.Pm10)
+		cw.add(FieldHumidity, m.Humidity)
+	}
+	fmt.Fprintf(w, "station history: %d records\n", len(records))
+	if corrected > 0 {
+		fmt.Fprintf(w, "warning: %d records have no raw values, disable PM correction (-c) "+
+			"while collecting calibration data\n", corrected)
+	}
+
+	f, err := os.Open(referenceFile)
+	if err != nil {
+		return err
+	}
+	defer CloseQuietly(f)
+	reference, err := readReferenceCsv(f, window, referenceOffset)
+	if err != nil {
+		return fmt.Errorf("can't read reference file %s: %v", referenceFile, err)
+	}
+
+	profile := &PmCorrectionProfile{}
+	for _, field := range []string{FieldPm25, FieldPm10} {
+		samples := alignCalibrationSamples(station, reference, field)
+		fmt.Fprintf(w, "\n%s: %d aligned %v samples\n", field, len(samples), window)
+		if len(samples) < calibrationMinSamples {
+			fmt.Fprintf(w, "  not enough samples (at least %d are required), skipped\n", calibrationMinSamples)
+			continue
+		}
+
+		identity := &PmCorrectionModel{Model: PmCorrectionLinear, Slope: 1}
+		names := []string{"uncorrected"}
+		models := []*PmCorrectionModel{identity}
+		regressors := []int{0}
+		if linear, err := FitLinearCorrection(samples, false); err == nil {
+			names, models, regressors = append(names, "linear"), append(models, linear), append(regressors, 1)
+		} else {
+			fmt.Fprintf(w, "  can't fit linear model: %v\n", err)
+		}
+		if linearRh, err := FitLinearCorrection(samples, true); err == nil {
+			names, models = append(names, "linear + humidity"), append(models, linearRh)
+			regressors = append(regressors, 2)
+		} else {
+			fmt.Fprintf(w, "  can't fit linear + humidity model: %v\n", err)
+		}
+
+		// In-sample error never grows with extra regressors, so models are compared by adjusted R²
+		var best *PmCorrectionModel
+		var bestStats CalibrationStats
+		fmt.Fprintf(w, "  %-18s %8s %8s %8s %8s %8s %8s %8s\n", "model", "slope", "humidity", "intercept",
+			"R²", "adj. R²", "RMSE", "bias")
+		for i, m := range models {
+			stats := CalibrationModelStats(m, samples, regressors[i])
+			fmt.Fprintf(w, "  %-18s %8.4f %8.4f %9.4f %8.3f %8.3f %8.2f %8.2f\n", names[i], m.Slope,
+				m.Humidity, m.Intercept, stats.R2, stats.AdjustedR2, stats.Rmse, stats.Bias)
+			if m != identity && (best == nil || stats.AdjustedR2 > bestStats.AdjustedR2) {
+				best, bestStats = m, stats
+			}
+		}
+		if best == nil {
+			fmt.Fprintf(w, "  can't fit correction model, skipped\n")
+			continue
+		}
+		if field == FieldPm25 {
+			profile.Pm25 = best
+		} else {
+			profile.Pm10 = best
+		}
+	}
+
+	if profile.Pm25 == nil && profile.Pm10 == nil {
+		return errors.New("no correction models fitted")
+	}
+
+	if output != "" {
+		if err := profile.Save(output); err != nil {
+			return fmt.Errorf("can't save correction profile: %v", err)
+		}
+		fmt.Fprintf(w, "\ncorrection profile with best fitting (highest adjusted R²) models is written to %s\n", output)
+	}
+
+	return nil
+}
+
+// readReferenceCsv reads reference values CSV file to time windows
+func readReferenceCsv(r io.Reader, window, offset time.Duration) (calibrationWindows, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int)
+	for i, h := range header {
+		columns[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	timeColumn := -1
+	for _, c := range referenceTimeColumns {
+		if i, ok := columns[c]; ok {
+			timeColumn = i
+			break
+		}
+	}
+	if timeColumn < 0 {
+		return nil, fmt.Errorf("no time column (%s)", SliceToString(referenceTimeColumns))
+	}
+
+	reference := make(calibrationWindows)
+	for line := 2; ; line++ {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		t, err := parseReferenceTime(row[timeColumn])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		cw := reference.window(t.Add(offset), window)
+		for _, field := range []string{FieldPm25, FieldPm10} {
+			i, ok := columns[field]
+			if !ok || i >= len(row) {
+				continue
+			}
+			v, err := strconv.ParseFloat(strings.TrimSpace(row[i]), 32)
+			if err != nil || v < 0 {
+				// Missing or invalid reference value
+				continue
+			}
+			f := float32(v)
+			cw.add(field, &f)
+		}
+	}
+	return reference, nil
+}
+
+func parseReferenceTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if ts, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(ts, 0), nil
+	}
+	for _, layout := range referenceTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time: %q", s)
+}
+
+// alignCalibrationSamples returns samples for time windows having both station and reference values
+func alignCalibrationSamples(station, reference calibrationWindows, field string) []calibrationSample {
+	var times []int64
+	for t := range station {
+		times = append(times, t)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+
+	var samples []calibrationSample
+	for _, t := range times {
+		rw, ok := reference[t]
+		if !ok {
+			continue
+		}
+		sw := station[t]
+		sv, ok := sw.mean(field)
+		if !ok {
+			continue
+		}
+		rv, ok := rw.mean(field)
+		if !ok {
+			continue
+		}
+		// Humidity is required by humidity correction model only
+		rh, hasHumidity := sw.mean(FieldHumidity)
+		samples = append(samples, calibrationSample{station: sv, humidity: rh, hasHumidity: hasHumidity,
+			reference: rv})
+	}
+	return samples
+}
+
+// FitLinearCorrection fits linear correction model of station values (and humidity)
+// to reference values using ordinary least squares
+func FitLinearCorrection(samples []calibrationSample, withHumidity bool) (*PmCorrectionModel, error) {
+	xs := make([][]float64, len(samples))
+	ys := make([]float64, len(samples))
+	for i, s := range samples {
+		if withHumidity {
+			if !s.hasHumidity {
+				return nil, errors.New("not all samples have humidity values")
+			}
+			xs[i] = []float64{s.station, s.humidity, 1}
+		} else {
+			xs[i] = []float64{s.station, 1}
+		}
+		ys[i] = s.reference
+	}
+	c, err := leastSquares(xs, ys)
+	if err != nil {
+		return nil, err
+	}
+	m := &PmCorrectionModel{Model: PmCorrectionLinear, Slope: c[0], Intercept: c[len(c)-1]}
+	if withHumidity {
+		m.Humidity = c[1]
+	}
+	if m.Slope <= 0 {
+		return nil, fmt.Errorf("non-positive slope: %v", m.Slope)
+	}
+	return m, nil
+}
+
+// CalibrationModelStats calculates fit statistics of correction model with given number
+// of fitted regressors (intercept excluded) for given samples
+func CalibrationModelStats(m *PmCorrectionModel, samples []calibrationSample, regressors int) CalibrationStats {
+	stats := CalibrationStats{Samples: len(samples)}
+	if len(samples) == 0 {
+		return stats
+	}
+	var refMean float64
+	for _, s := range samples {
+		refMean += s.reference
+	}
+	refMean /= float64(len(samples))
+	var ssRes, ssTot, sumErr float64
+	for _, s := range samples {
+		// Evaluate model without output rounding and clamping
+		p := m.Slope*s.station + m.Humidity*s.humidity + m.Intercept
+		e := p - s.reference
+		ssRes += e * e
+		ssTot += (s.reference - refMean) * (s.reference - refMean)
+		sumErr += e
+	}
+	n := float64(len(samples))
+	stats.Rmse = math.Sqrt(ssRes / n)
+	stats.Bias = sumErr / n
+	if ssTot > 0 {
+		stats.R2 = 1 - ssRes/ssTot
+	}
+	stats.AdjustedR2 = stats.R2
+	if dof := n - float64(regressors) - 1; dof > 0 {
+		stats.AdjustedR2 = 1 - (1-stats.R2)*(n-1)/dof
+	}
+	return stats
+}
+
+// leastSquares solves linear least squares problem using normal equations
+func leastSquares(xs [][]float64, ys []float64) ([]float64, error) {
+	if len(xs) == 0 {
+		return nil, errors.New("no samples")
+	}
+	k := len(xs[0])
+	// Augmented normal equations matrix [XᵀX | Xᵀy]
+	a := make([][]float64, k)
+	for i := range a {
+		a[i] = make([]float64, k+1)
+	}
+	for n, x := range xs {
+		for i := 0; i < k; i++ {
+			for j := 0; j < k; j++ {
+				a[i][j] += x[i] * x[j]
+			}
+			a[i][k] += x[i] * ys[n]
+		}
+	}
+	// Gaussian elimination with partial pivoting
+	for c := 0; c < k; c++ {
+		p := c
+		for r := c + 1; r < k; r++ {
+			if math.Abs(a[r][c]) > math.Abs(a[p][c]) {
+				p = r
+			}
+		}
+		if math.Abs(a[p][c]) < 1e-12 {
+			return nil, errors.New("singular matrix")
+		}
+		a[c], a[p] = a[p], a[c]
+		for r := c + 1; r < k; r++ {
+			f := a[r][c] / a[c][c]
+			for j := c; j <= k; j++ {
+				a[r][j] -= f * a[c][j]
+			}
+		}
+	}
+	x := make([]float64, k)
+	for i := k - 1; i >= 0; i-- {
+		s := a[i][k]
+		for j := i + 1; j < k; j++ {
+			s -= a[i][j] * x[j]
+		}
+		x[i] = s / a[i][i]
+	}
+	return x, nil
+}
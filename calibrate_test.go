@@ -0,0 +1,133 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openairtech/api"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFitLinearCorrection(t *testing.T) {
+	var samples []calibrationSample
+	for i := 0; i < 30; i++ {
+		pm, rh := float64(5+i*2), float64(40+(i*7)%50)
+		samples = append(samples, calibrationSample{station: pm, humidity: rh, hasHumidity: true,
+			reference: 0.6*pm - 0.1*rh + 4})
+	}
+
+	m, err := FitLinearCorrection(samples, true)
+	require.NoError(t, err)
+	require.InDelta(t, 0.6, m.Slope, 1e-6)
+	require.InDelta(t, -0.1, m.Humidity, 1e-6)
+	require.InDelta(t, 4, m.Intercept, 1e-6)
+
+	stats := CalibrationModelStats(m, samples, 2)
+	require.Equal(t, 30, stats.Samples)
+	require.InDelta(t, 1, stats.R2, 1e-9)
+	require.InDelta(t, 1, stats.AdjustedR2, 1e-9)
+	require.InDelta(t, 0, stats.Rmse, 1e-6)
+	require.InDelta(t, 0, stats.Bias, 1e-6)
+
+	// Linear model without humidity can't fit humidity dependent values exactly
+	m, err = FitLinearCorrection(samples, false)
+	require.NoError(t, err)
+	require.Zero(t, m.Humidity)
+	stats = CalibrationModelStats(m, samples, 1)
+	require.Greater(t, stats.Rmse, 0.1)
+	require.Less(t, stats.R2, 1.0)
+	require.Less(t, stats.AdjustedR2, stats.R2)
+
+	_, err = FitLinearCorrection(samples[:1], false)
+	require.Error(t, err)
+
+	// Humidity model requires humidity in all samples, linear model doesn't
+	samples[3].hasHumidity = false
+	_, err = FitLinearCorrection(samples, true)
+	require.Error(t, err)
+	_, err = FitLinearCorrection(samples, false)
+	require.NoError(t, err)
+}
+
+func TestCalibrate(t *testing.T) {
+	dir := t.TempDir()
+	h := NewHistory(filepath.Join(dir, "history"), 30*24*time.Hour)
+	require.NoError(t, h.Start())
+	hn := NewHistory(filepath.Join(dir, "history-no-rh"), 30*24*time.Hour)
+	require.NoError(t, hn.Start())
+
+	start := time.Date(2026, 4, 1, 0, 0, 0, 0, time.Local)
+	var csv strings.Builder
+	csv.WriteString("Time,PM25,PM10\n")
+	for i := 0; i < 48; i++ {
+		pm25, pm10, rh := float32(10+i%12), float32(20+i%12), float32(50+i%5)
+		// Station measurements every 20 minutes
+		for j := 0; j < 3; j++ {
+			ts := api.UnixTime(start.Add(time.Duration(i)*time.Hour + time.Duration(j)*20*time.Minute))
+			raw := &api.Measurement{Timestamp: &ts, Pm25: &pm25, Pm10: &pm10, Humidity: &rh}
+			h.Publish(&StationData{LastMeasurement: raw, RawMeasurement: raw})
+			// Station without humidity sensor
+			raw = &api.Measurement{Timestamp: &ts, Pm25: &pm25, Pm10: &pm10}
+			hn.Publish(&StationData{LastMeasurement: raw, RawMeasurement: raw})
+		}
+		// Hourly reference values labeled with averaging period end,
+		// with small humidity independent measurement noise
+		noise := float32(i%7-3) * 0.02
+		_, _ = fmt.Fprintf(&csv, "%s,%.2f,%.2f\n", start.Add(time.Duration(i+1)*time.Hour).Format(time.RFC3339),
+			0.5*pm25+1+noise, 0.8*pm10-noise)
+	}
+	// Missing reference value
+	csv.WriteString(start.Add(49*time.Hour).Format("2006-01-02 15:04:05") + ",,\n")
+	referenceFile := filepath.Join(dir, "reference.csv")
+	require.NoError(t, os.WriteFile(referenceFile, []byte(csv.String()), 0644))
+
+	profileFile := filepath.Join(dir, "profile.json")
+	var report bytes.Buffer
+	require.NoError(t, calibrate(&report, h.Dir(), referenceFile, -time.Hour, profileFile, time.Hour, "", ""))
+	require.Contains(t, report.String(), "pm25: 48 aligned 1h0m0s samples")
+
+	cp, err := LoadPmCorrectionProfile(profileFile)
+	require.NoError(t, err)
+	require.Equal(t, PmCorrectionLinear, cp.Pm25.Model)
+	require.InDelta(t, 0.5, cp.Pm25.Slope, 1e-2)
+	require.InDelta(t, 1, cp.Pm25.Intercept, 1e-1)
+	require.InDelta(t, 0.8, cp.Pm10.Slope, 1e-2)
+	require.InDelta(t, 0, cp.Pm10.Intercept, 1e-1)
+	// Humidity model isn't chosen when humidity doesn't explain reference values
+	require.Zero(t, cp.Pm25.Humidity)
+	require.Zero(t, cp.Pm10.Humidity)
+
+	// Station without humidity values is calibrated with linear model
+	report.Reset()
+	require.NoError(t, calibrate(&report, hn.Dir(), referenceFile, -time.Hour, profileFile, time.Hour, "", ""))
+	require.Contains(t, report.String(), "pm25: 48 aligned 1h0m0s samples")
+	require.Contains(t, report.String(), "can't fit linear + humidity model")
+	cp, err = LoadPmCorrectionProfile(profileFile)
+	require.NoError(t, err)
+	require.InDelta(t, 0.5, cp.Pm25.Slope, 1e-2)
+	require.Zero(t, cp.Pm25.Humidity)
+
+	// Not enough aligned samples for the period
+	require.Error(t, calibrate(&report, h.Dir(), referenceFile, -time.Hour, "", time.Hour,
+		"2026-04-02", "2026-04-02"))
+}
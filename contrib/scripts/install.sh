@@ -203,6 +203,7 @@ Description=OpenAir Station
 After=network.target
 
 [Service]
+EnvironmentFile=-/etc/default/${BIN}
 ExecStart=${BIN_PATH} \$STATION_EXEC_OPTIONS
 Restart=always
 RestartSec=5
@@ -86,6 +86,13 @@ cd ${TMPDIR} || :
 
 setup_arch
 
+# Units installed before station configuration file support don't read it
+UNIT_FILE="/etc/systemd/system/${BIN}.service"
+if [[ -f "${UNIT_FILE}" ]] && ! grep -q "^EnvironmentFile=.*/etc/default/${BIN}" "${UNIT_FILE}"; then
+    sed -i "/^\[Service\]/a EnvironmentFile=-/etc/default/${BIN}" "${UNIT_FILE}" || fatal "can't update ${UNIT_FILE}"
+    systemctl daemon-reload
+fi
+
 download "${SHA_URL}" "${TMPDIR}/sha256sum.txt"
 
 REMOTE_SHASUM=`awk '{ print $1 }' ${TMPDIR}/sha256sum.txt`
@@ -0,0 +1,197 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+
+	"github.com/openairtech/api"
+)
+
+// PM humidity correction models
+const (
+	// Empirical hygroscopic growth factor: pm / (1 + a * (rh/100)^b)
+	PmCorrectionGrowth = "growth"
+	// κ-Köhler hygroscopic growth (Crilley et al., 2018): pm / (1 + (κ/1.65) / (100/rh - 1))
+	PmCorrectionKohler = "kohler"
+	// US EPA US-wide PurpleAir PM2.5 correction (Barkjohn et al., 2021): 0.524 * pm - 0.0862 * rh + 5.75
+	PmCorrectionEpa = "epa"
+	// Linear regression: slope * pm + humidity * rh + intercept
+	PmCorrectionLinear = "linear"
+)
+
+func PmCorrectionModelList() []string {
+	return []string{PmCorrectionGrowth, PmCorrectionKohler, PmCorrectionEpa, PmCorrectionLinear}
+}
+
+const (
+	// Default κ-Köhler hygroscopicity parameter
+	defaultKohlerKappa = 0.4
+	// Max relative humidity value used in hygroscopic growth models
+	pmCorrectionMaxHumidity = 99
+)
+
+// PmCorrectionModel is the PM value correction model with its coefficients,
+// zero coefficients are replaced with model defaults
+type PmCorrectionModel struct {
+	Model string `json:"model"`
+	// Growth model coefficients
+	A float64 `json:"a,omitempty"`
+	B float64 `json:"b,omitempty"`
+	// κ-Köhler model hygroscopicity parameter
+	Kappa float64 `json:"kappa,omitempty"`
+	// Linear model coefficients
+	Slope     float64 `json:"slope,omitempty"`
+	Intercept float64 `json:"intercept,omitempty"`
+	Humidity  float64 `json:"humidity,omitempty"`
+}
+
+// PmCorrectionProfile is the station PM2.5 and PM10 values correction profile,
+// values without model are not corrected
+type PmCorrectionProfile struct {
+	Pm25 *PmCorrectionModel `json:"pm25,omitempty"`
+	Pm10 *PmCorrectionModel `json:"pm10,omitempty"`
+}
+
+// NewPmCorrectionProfile creates correction profile using given model
+// with default coefficients for both PM2.5 and PM10 values
+func NewPmCorrectionProfile(model string) (*PmCorrectionProfile, error) {
+	cp := &PmCorrectionProfile{
+		Pm25: &PmCorrectionModel{Model: model},
+	}
+	// EPA correction is defined for PM2.5 only
+	if model != PmCorrectionEpa {
+		cp.Pm10 = &PmCorrectionModel{Model: model}
+	}
+	if err := cp.init(); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// LoadPmCorrectionProfile loads correction profile from given JSON file
+func LoadPmCorrectionProfile(fn string) (*PmCorrectionProfile, error) {
+	b, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	var cp PmCorrectionProfile
+	if err := json.Unmarshal(b, &cp); err != nil {
+		return nil, fmt.Errorf("can't parse PM correction profile file %s: %v", fn, err)
+	}
+	if err := cp.init(); err != nil {
+		return nil, fmt.Errorf("invalid PM correction profile file %s: %v", fn, err)
+	}
+	return &cp, nil
+}
+
+// Save saves correction profile to given JSON file
+func (cp *PmCorrectionProfile) Save(fn string) error {
+	b, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fn, append(b, '\n'), 0644)
+}
+
+// init validates profile models and sets default model coefficients
+func (cp *PmCorrectionProfile) init() error {
+	if cp.Pm25 != nil {
+		if err := cp.Pm25.init(FieldPm25); err != nil {
+			return fmt.Errorf("%s: %v", FieldPm25, err)
+		}
+	}
+	if cp.Pm10 != nil {
+		if err := cp.Pm10.init(FieldPm10); err != nil {
+			return fmt.Errorf("%s: %v", FieldPm10, err)
+		}
+	}
+	return nil
+}
+
+func (cm *PmCorrectionModel) init(field string) error {
+	switch cm.Model {
+	case PmCorrectionGrowth:
+		if cm.A == 0 && cm.B == 0 {
+			if field == FieldPm25 {
+				cm.A, cm.B = 0.48756, 8.60068
+			} else {
+				cm.A, cm.B = 0.81559, 5.83411
+			}
+		}
+	case PmCorrectionKohler:
+		if cm.Kappa == 0 {
+			cm.Kappa = defaultKohlerKappa
+		}
+	case PmCorrectionEpa:
+		if field != FieldPm25 {
+			return fmt.Errorf("%s correction model is defined for PM2.5 only", cm.Model)
+		}
+		if cm.Slope == 0 && cm.Humidity == 0 && cm.Intercept == 0 {
+			cm.Slope, cm.Humidity, cm.Intercept = 0.524, -0.0862, 5.75
+		}
+	case PmCorrectionLinear:
+		if cm.Slope == 0 {
+			return fmt.Errorf("%s correction model slope is not set", cm.Model)
+		}
+	default:
+		return fmt.Errorf("unknown correction model: %s (known models: %s)", cm.Model,
+			SliceToString(PmCorrectionModelList()))
+	}
+	return nil
+}
+
+// Correct returns corrected PM value for given relative humidity or false
+// if value can't be corrected since model requires unknown humidity
+func (cm *PmCorrectionModel) Correct(pm float32, humidity *float32) (float32, bool) {
+	var rh float64
+	if humidity != nil {
+		rh = math.Min(math.Max(float64(*humidity), 0), pmCorrectionMaxHumidity)
+	} else if cm.Model != PmCorrectionLinear || cm.Humidity != 0 {
+		return pm, false
+	}
+
+	var v float64
+	switch cm.Model {
+	case PmCorrectionGrowth:
+		v = float64(pm) / (1 + cm.A*math.Pow(rh/100, cm.B))
+	case PmCorrectionKohler:
+		v = float64(pm)
+		if rh > 0 {
+			v /= 1 + (cm.Kappa/1.65)/(100/rh-1)
+		}
+	default:
+		v = cm.Slope*float64(pm) + cm.Humidity*rh + cm.Intercept
+	}
+
+	return Float32Round(float32(math.Max(v, 0)), 1), true
+}
+
+// Correct corrects measurement PM values
+func (cp *PmCorrectionProfile) Correct(m *api.Measurement) {
+	if cp.Pm25 != nil && m.Pm25 != nil {
+		if v, ok := cp.Pm25.Correct(*m.Pm25, m.Humidity); ok {
+			m.Pm25 = &v
+		}
+	}
+	if cp.Pm10 != nil && m.Pm10 != nil {
+		if v, ok := cp.Pm10.Correct(*m.Pm10, m.Humidity); ok {
+			m.Pm10 = &v
+		}
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/openairtech/api"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testPmMeasurement(pm25, pm10 float32, humidity *float32) *api.Measurement {
+	return &api.Measurement{Pm25: &pm25, Pm10: &pm10, Humidity: humidity}
+}
+
+func TestPmCorrectionProfile_Correct(t *testing.T) {
+	humidity := float32(90)
+	tests := []struct {
+		model      string
+		humidity   *float32
+		pm25, pm10 float32
+	}{
+		{PmCorrectionGrowth, &humidity, 33.4, 34.7},
+		{PmCorrectionKohler, &humidity, 12.6, 15.7},
+		// PM10 is not corrected
+		{PmCorrectionEpa, &humidity, 19, 50},
+		// Humidity is required
+		{PmCorrectionGrowth, nil, 40, 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			cp, err := NewPmCorrectionProfile(tt.model)
+			require.NoError(t, err)
+			m := testPmMeasurement(40, 50, tt.humidity)
+			cp.Correct(m)
+			require.Equal(t, tt.pm25, *m.Pm25)
+			require.Equal(t, tt.pm10, *m.Pm10)
+		})
+	}
+
+	_, err := NewPmCorrectionProfile(PmCorrectionLinear)
+	require.Error(t, err, "linear model requires coefficients")
+	_, err = NewPmCorrectionProfile("unknown")
+	require.Error(t, err)
+}
+
+func TestLoadPmCorrectionProfile(t *testing.T) {
+	cp, err := LoadPmCorrectionProfile(filepath.Join("testdata", "pm-calibration.json"))
+	require.NoError(t, err)
+
+	humidity := float32(50)
+	m := testPmMeasurement(10, 20, &humidity)
+	cp.Correct(m)
+	require.Equal(t, float32(4.7), *m.Pm25)
+	require.Equal(t, float32(16.9), *m.Pm10)
+
+	fn := filepath.Join(t.TempDir(), "profile.json")
+	require.NoError(t, cp.Save(fn))
+	saved, err := LoadPmCorrectionProfile(fn)
+	require.NoError(t, err)
+	require.Equal(t, cp, saved)
+
+	// EPA model coefficients set in profile are kept
+	require.NoError(t, ioutil.WriteFile(fn, []byte(`{"pm25": {"model": "epa", "slope": 0.5, "humidity": -0.1, `+
+		`"intercept": 5}}`), 0644))
+	cp, err = LoadPmCorrectionProfile(fn)
+	require.NoError(t, err)
+	require.Equal(t, 0.5, cp.Pm25.Slope)
+	require.Equal(t, -0.1, cp.Pm25.Humidity)
+	require.Equal(t, 5.0, cp.Pm25.Intercept)
+}
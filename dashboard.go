@@ -0,0 +1,102 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// Default dashboard history chart period
+	dashboardHistoryPeriod = 24 * time.Hour
+	// Max dashboard history chart period
+	dashboardMaxHistoryPeriod = 7 * 24 * time.Hour
+)
+
+//go:embed web
+var dashboardFiles embed.FS
+
+// registerDashboard registers dashboard static files and API handlers
+func (hp *HttpPublisher) registerDashboard(mux *http.ServeMux) {
+	webFiles, err := fs.Sub(dashboardFiles, "web")
+	if err != nil {
+		log.Errorf("can't open dashboard files: %v", err)
+		return
+	}
+	mux.Handle("/", http.FileServer(http.FS(webFiles)))
+
+	mux.HandleFunc("/api/data", func(w http.ResponseWriter, r *http.Request) {
+		ld := hp.data()
+		if ld == nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		writeJson(w, ld)
+	})
+
+	mux.HandleFunc("/api/history", func(w http.ResponseWriter, r *http.Request) {
+		if hp.history == nil {
+			http.Error(w, "measurements history is disabled", http.StatusNotFound)
+			return
+		}
+		period := dashboardHistoryPeriod
+		if p := r.URL.Query().Get("period"); p != "" {
+			d, err := time.ParseDuration(p)
+			if err != nil || d <= 0 {
+				http.Error(w, "invalid period", http.StatusBadRequest)
+				return
+			}
+			if period = d; period > dashboardMaxHistoryPeriod {
+				period = dashboardMaxHistoryPeriod
+			}
+		}
+		now := time.Now()
+		records, err := hp.history.Records(now.Add(-period), now.Add(time.Second), 0)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		ms := make([]*MeasurementJson, len(records))
+		for i := range records {
+			ms[i] = NewMeasurementJson(&records[i].Measurement)
+		}
+		writeJson(w, ms)
+	})
+
+	mux.HandleFunc("/api/log", func(w http.ResponseWriter, r *http.Request) {
+		if hp.logBuffer == nil {
+			writeJson(w, []LogEntry{})
+			return
+		}
+		writeJson(w, hp.logBuffer.Entries())
+	})
+}
+
+func writeJson(w http.ResponseWriter, v interface{}) {
+	jd, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(jd)
+}
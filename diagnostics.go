@@ -0,0 +1,243 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/openairtech/api"
+)
+
+// Data quality flags
+const (
+	QualityStuck         = "stuck"
+	QualitySaturated     = "saturated"
+	QualityPmRatio       = "pm25_exceeds_pm10"
+	QualityHumidityRange = "humidity_out_of_range"
+	QualityJump          = "jump"
+
+	QualityFlagAll = "all"
+)
+
+func QualityFlagList() []string {
+	return []string{QualityStuck, QualitySaturated, QualityPmRatio, QualityHumidityRange, QualityJump}
+}
+
+// QualityFlag is the detected sensor fault or suspicious measurement value
+type QualityFlag struct {
+	Flag    string `json:"flag"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+func (qf QualityFlag) key() string {
+	return qf.Flag + "/" + qf.Field
+}
+
+// Sensor measurement limits, values at limits are most likely saturated (SDS011 and BME280 sensors)
+var sensorLimits = map[string]ValueRange{
+	FieldTemperature: {Min: -40, Max: 85},
+	FieldPressure:    {Min: 300, Max: 1100},
+	FieldPm25:        {Min: -math.MaxFloat32, Max: 999.9},
+	FieldPm10:        {Min: -math.MaxFloat32, Max: 1999.9},
+}
+
+// Max change of measurement field value between consecutive measurements
+var maxValueJumps = map[string]float32{
+	FieldTemperature: 10,
+	FieldHumidity:    30,
+	FieldPressure:    10,
+	FieldPm25:        300,
+	FieldPm10:        500,
+}
+
+type diagnosticsValue struct {
+	value float32
+	since time.Time
+}
+
+// Diagnostics detects sensor faults by measurement values and logs diagnosis on fault detection and clearing
+type Diagnostics struct {
+	stuckTime time.Duration
+
+	last   map[string]*diagnosticsValue
+	active map[string]QualityFlag
+}
+
+// NewDiagnostics creates diagnostics detecting values unchanged for given time as stuck (0 to disable)
+func NewDiagnostics(stuckTime time.Duration) *Diagnostics {
+	return &Diagnostics{
+		stuckTime: stuckTime,
+		last:      make(map[string]*diagnosticsValue),
+		active:    make(map[string]QualityFlag),
+	}
+}
+
+// Diagnose returns quality flags for given measurement taken at given time
+func (d *Diagnostics) Diagnose(m *api.Measurement, now time.Time) []QualityFlag {
+	var flags []QualityFlag
+
+	for _, field := range MeasurementFieldList() {
+		ref := *MeasurementFieldRef(m, field)
+		if ref == nil {
+			continue
+		}
+		v := *ref
+
+		if limits, ok := sensorLimits[field]; ok && (v <= limits.Min || v >= limits.Max) {
+			flags = append(flags, QualityFlag{Flag: QualitySaturated, Field: field,
+				Message: fmt.Sprintf("%s value %g is at sensor limit", field, v)})
+		}
+
+		last, ok := d.last[field]
+		if !ok {
+			d.last[field] = &diagnosticsValue{value: v, since: now}
+			continue
+		}
+		if maxJump, ok := maxValueJumps[field]; ok && float32(math.Abs(float64(v-last.value))) > maxJump {
+			flags = append(flags, QualityFlag{Flag: QualityJump, Field: field,
+				Message: fmt.Sprintf("%s value jumped from %g to %g", field, last.value, v)})
+		}
+		if v != last.value {
+			last.value, last.since = v, now
+		} else if d.stuckTime > 0 && now.Sub(last.since) >= d.stuckTime {
+			flags = append(flags, QualityFlag{Flag: QualityStuck, Field: field,
+				Message: fmt.Sprintf("%s value is stuck at %g for %v", field, v,
+					now.Sub(last.since).Round(time.Minute))})
+		}
+	}
+
+	if m.Humidity != nil && (*m.Humidity < 0 || *m.Humidity > 100) {
+		flags = append(flags, QualityFlag{Flag: QualityHumidityRange, Field: FieldHumidity,
+			Message: fmt.Sprintf("humidity value %g is out of 0-100%% range", *m.Humidity)})
+	}
+
+	if m.Pm25 != nil && m.Pm10 != nil && *m.Pm25 > *m.Pm10 {
+		flags = append(flags, QualityFlag{Flag: QualityPmRatio,
+			Message: fmt.Sprintf("PM2.5 value %g exceeds PM10 value %g", *m.Pm25, *m.Pm10)})
+	}
+
+	d.update(flags)
+
+	return flags
+}
+
+// update logs and updates metrics of detected and cleared faults
+func (d *Diagnostics) update(flags []QualityFlag) {
+	detected := make(map[string]bool)
+	for _, f := range flags {
+		k := f.key()
+		detected[k] = true
+		if _, ok := d.active[k]; !ok {
+			log.Warnf("sensor fault detected: %s", f.Message)
+			metricQualityFlags.Set(1, "flag", f.Flag, "field", f.Field)
+		}
+		d.active[k] = f
+	}
+	for k, f := range d.active {
+		if detected[k] {
+			continue
+		}
+		log.Infof("sensor fault cleared: %s", f.Message)
+		metricQualityFlags.Delete("flag", f.Flag, "field", f.Field)
+		delete(d.active, k)
+	}
+}
+
+// MergeQualityFlags adds given flags to the list skipping already listed ones
+func MergeQualityFlags(list []QualityFlag, flags []QualityFlag) []QualityFlag {
+	for _, f := range flags {
+		found := false
+		for _, lf := range list {
+			if lf.key() == f.key() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, f)
+		}
+	}
+	return list
+}
+
+// QualityFeeder drops station data having given quality flags instead of feeding it to the feeder
+type QualityFeeder struct {
+	Feeder
+
+	name  string
+	flags []string
+}
+
+// QualityDropper is the feeder posting measurements from history
+// which should skip ones having dropped quality flags
+type QualityDropper interface {
+	SetQualityDrop(flags []string)
+}
+
+// NewQualityFeeder creates feeder wrapper dropping data with given quality flags ("all" for any flag)
+func NewQualityFeeder(feeder Feeder, name string, flags []string) *QualityFeeder {
+	if qd, ok := feeder.(QualityDropper); ok {
+		qd.SetQualityDrop(flags)
+	}
+	return &QualityFeeder{Feeder: feeder, name: name, flags: flags}
+}
+
+func (qf *QualityFeeder) Feed(data *StationData) {
+	if f := DroppedQualityFlag(data.Quality, qf.flags); f != nil {
+		log.Warnf("%s feeder: dropping measurement: %s", qf.name, f.Message)
+		return
+	}
+	qf.Feeder.Feed(data)
+}
+
+// DroppedQualityFlag returns the first of given quality flags listed in drop flags
+// ("all" for any flag) or nil if there is no such flag
+func DroppedQualityFlag(quality []QualityFlag, drop []string) *QualityFlag {
+	for i, f := range quality {
+		if StringInSlice(QualityFlagAll, drop) || StringInSlice(f.Flag, drop) {
+			return &quality[i]
+		}
+	}
+	return nil
+}
+
+// ParseQualityDrop parses comma-separated list of feeder=flag1|flag2 pairs
+func ParseQualityDrop(s string) (map[string][]string, error) {
+	kv, err := ParseKeyValueList(s)
+	if err != nil {
+		return nil, err
+	}
+	drop := make(map[string][]string)
+	for feeder, v := range kv {
+		if !StringInSlice(feeder, FeederNameList()) {
+			return nil, fmt.Errorf("unknown feeder: %s", feeder)
+		}
+		for _, f := range strings.Split(v, "|") {
+			f = strings.TrimSpace(f)
+			if f != QualityFlagAll && !StringInSlice(f, QualityFlagList()) {
+				return nil, fmt.Errorf("unknown quality flag: %s (known flags: %s)", f,
+					SliceToString(QualityFlagList()))
+			}
+			drop[feeder] = append(drop[feeder], f)
+		}
+	}
+	return drop, nil
+}
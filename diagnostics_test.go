@@ -0,0 +1,90 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openairtech/api"
+
+	"github.com/stretchr/testify/require"
+)
+
+func qualityFlagNames(flags []QualityFlag) []string {
+	var names []string
+	for _, f := range flags {
+		names = append(names, f.Flag+"/"+f.Field)
+	}
+	return names
+}
+
+func TestDiagnostics_Diagnose(t *testing.T) {
+	d := NewDiagnostics(time.Hour)
+	f := func(v float32) *float32 { return &v }
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	require.Empty(t, d.Diagnose(&api.Measurement{Temperature: f(20), Humidity: f(50), Pm25: f(5), Pm10: f(8)}, now))
+
+	flags := d.Diagnose(&api.Measurement{Temperature: f(20), Humidity: f(101), Pm25: f(999.9), Pm10: f(9)},
+		now.Add(30*time.Minute))
+	require.Equal(t, []string{"jump/humidity", "saturated/pm25", "jump/pm25", "humidity_out_of_range/humidity",
+		"pm25_exceeds_pm10/"}, qualityFlagNames(flags))
+	require.Equal(t, "PM2.5 value 999.9 exceeds PM10 value 9", flags[4].Message)
+	require.Len(t, d.active, 5)
+
+	flags = d.Diagnose(&api.Measurement{Temperature: f(20), Humidity: f(95), Pm25: f(5), Pm10: f(9)},
+		now.Add(time.Hour))
+	require.Equal(t, []string{"stuck/temperature", "jump/pm25"}, qualityFlagNames(flags))
+	require.Equal(t, "temperature value is stuck at 20 for 1h0m0s", flags[0].Message)
+	require.Len(t, d.active, 2)
+
+	require.Empty(t, d.Diagnose(&api.Measurement{Temperature: f(20.1), Humidity: f(94)}, now.Add(2*time.Hour)))
+	require.Empty(t, d.active)
+}
+
+type testFeeder struct {
+	fed int
+}
+
+func (tf *testFeeder) Feed(*StationData) {
+	tf.fed++
+}
+
+func (tf *testFeeder) Status() FeederStatus {
+	return FeederStatus{Name: "test"}
+}
+
+func TestQualityFeeder(t *testing.T) {
+	drop, err := ParseQualityDrop("luftdaten=stuck|saturated, openair=all")
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{
+		FeederLuftdaten: {QualityStuck, QualitySaturated},
+		FeederOpenAir:   {QualityFlagAll},
+	}, drop)
+
+	_, err = ParseQualityDrop("luftdaten=broken")
+	require.Error(t, err)
+	_, err = ParseQualityDrop("unknown=all")
+	require.Error(t, err)
+
+	tf := &testFeeder{}
+	qf := NewQualityFeeder(tf, FeederLuftdaten, drop[FeederLuftdaten])
+	qf.Feed(&StationData{})
+	qf.Feed(&StationData{Quality: []QualityFlag{{Flag: QualityJump, Field: FieldPm25}}})
+	qf.Feed(&StationData{Quality: []QualityFlag{{Flag: QualityStuck, Field: FieldPm25}}})
+	require.Equal(t, 2, tf.fed)
+	require.Equal(t, "test", qf.Status().Name)
+}
@@ -0,0 +1,292 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/grandcat/zeroconf"
+)
+
+const (
+	CommandDiscover = "discover"
+
+	// Station service environment file read by systemd unit
+	stationConfigFile = "/etc/default/openair-station"
+	// Station service systemd unit file
+	stationServiceUnitFile = "/etc/systemd/system/openair-station.service"
+	// Station service environment variable with station command line options
+	stationExecOptionsVar = "STATION_EXEC_OPTIONS"
+)
+
+// DiscoveredStation is the ESP Easy unit found in local network
+type DiscoveredStation struct {
+	Host     string
+	Ip       string
+	Port     int
+	Mac      string
+	TokenId  string
+	Firmware string
+	Sensors  []string
+}
+
+// runDiscover runs discover command with given arguments and returns process exit code
+func runDiscover(args []string) int {
+	fs := flag.NewFlagSet(CommandDiscover, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s [options]\n\n"+
+			"Finds ESP Easy stations in local network using mDNS and optionally writes\n"+
+			"chosen station address into station service configuration.\n\n",
+			os.Args[0], CommandDiscover)
+		fs.PrintDefaults()
+	}
+	timeout := fs.Duration("t", 5*time.Second, "stations browse timeout")
+	write := fs.String("w", "", "host name or list number of discovered station to write into "+
+		"station service configuration")
+	config := fs.String("config", stationConfigFile, "station service configuration file")
+	_ = fs.Parse(args)
+
+	InitHttp(*timeout)
+
+	stations, err := discoverStations(*timeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "discovery failed: %v\n", err)
+		return 1
+	}
+
+	printDiscoveredStations(os.Stdout, stations)
+
+	if *write == "" {
+		return 0
+	}
+
+	ds := findDiscoveredStation(stations, *write)
+	if ds == nil {
+		fmt.Fprintf(os.Stderr, "station not found: %s\n", *write)
+		return 1
+	}
+	if err := writeStationConfig(*config, ds.Host, ds.Port); err != nil {
+		fmt.Fprintf(os.Stderr, "can't write station configuration: %v\n", err)
+		return 1
+	}
+	fmt.Printf("\nstation %s is written to %s, restart station service to apply\n", ds.Host, *config)
+	if ok, err := serviceUnitReadsConfig(stationServiceUnitFile, *config); err == nil && !ok {
+		fmt.Fprintf(os.Stderr, "warning: %s doesn't read %s, add \"EnvironmentFile=-%s\" to its [Service] "+
+			"section and run \"systemctl daemon-reload\" or rerun station update\n",
+			stationServiceUnitFile, *config, *config)
+	}
+	return 0
+}
+
+// discoverStations browses HTTP services in local network and probes them for ESP Easy units
+func discoverStations(timeout time.Duration) ([]*DiscoveredStation, error) {
+	resolver, err := zeroconf.NewResolver(nil)
+	if err != nil {
+		return nil, fmt.Errorf("can't create resolver: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+
+	defer cancel()
+
+	entries := make(chan *zeroconf.ServiceEntry)
+
+	if err := resolver.Browse(ctx, "_http._tcp", "local.", entries); err != nil {
+		return nil, err
+	}
+
+	services := make(map[string]*zeroconf.ServiceEntry)
+	for e := range entries {
+		services[e.Instance] = e
+	}
+
+	var stations []*DiscoveredStation
+	for _, e := range services {
+		addrs := append(append([]net.IP{}, e.AddrIPv4...), e.AddrIPv6...)
+		if len(addrs) == 0 {
+			continue
+		}
+		ds, err := probeEspStation(e.Instance+".local", addrs[0].String(), e.Port)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "skipping %s (%s): %v\n", e.Instance, addrs[0], err)
+			continue
+		}
+		stations = append(stations, ds)
+	}
+
+	sort.Slice(stations, func(i, j int) bool {
+		return strings.ToLower(stations[i].Host) < strings.ToLower(stations[j].Host)
+	})
+
+	return stations, nil
+}
+
+// probeEspStation requests ESP Easy JSON data from given address
+// and returns discovered station if it is ESP Easy unit
+func probeEspStation(host, ip string, port int) (*DiscoveredStation, error) {
+	var data EspData
+	if err := HttpGetData(fmt.Sprintf("http://%s/json", net.JoinHostPort(ip, strconv.Itoa(port))),
+		&data); err != nil {
+		return nil, err
+	}
+
+	if data.System == nil || data.WiFi == nil {
+		return nil, errors.New("not an ESP Easy unit")
+	}
+
+	ds := &DiscoveredStation{
+		Host:     host,
+		Ip:       ip,
+		Port:     port,
+		Mac:      data.WiFi.MacAddress(),
+		Firmware: data.System.FirmwareVersion(),
+	}
+	if ds.Mac != "" {
+		ds.TokenId = stationTokenId(ds.Mac)
+	}
+
+	for i, sm := range defaultEspMapping.Sensors(data.Sensors) {
+		if sm != nil {
+			ds.Sensors = append(ds.Sensors, data.Sensors[i].TaskName)
+		}
+	}
+
+	return ds, nil
+}
+
+func printDiscoveredStations(w io.Writer, stations []*DiscoveredStation) {
+	if len(stations) == 0 {
+		fmt.Fprintln(w, "no ESP Easy stations found")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "#\tHOST\tIP\tMAC\tTOKEN ID\tFIRMWARE\tSENSORS")
+	for i, ds := range stations {
+		sensors := "none"
+		if len(ds.Sensors) > 0 {
+			sensors = strings.Join(ds.Sensors, ", ")
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", i+1, ds.Host,
+			net.JoinHostPort(ds.Ip, strconv.Itoa(ds.Port)), ds.Mac, ds.TokenId, ds.Firmware, sensors)
+	}
+	_ = tw.Flush()
+}
+
+// findDiscoveredStation finds station by host name or list number
+func findDiscoveredStation(stations []*DiscoveredStation, s string) *DiscoveredStation {
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 1 || n > len(stations) {
+			return nil
+		}
+		return stations[n-1]
+	}
+	for _, ds := range stations {
+		if strings.EqualFold(ds.Host, s) || strings.EqualFold(strings.TrimSuffix(ds.Host, ".local"), s) {
+			return ds
+		}
+	}
+	return nil
+}
+
+// writeStationConfig sets ESP station address in given station service environment file
+func writeStationConfig(fn, host string, port int) error {
+	b, err := ioutil.ReadFile(fn)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return ioutil.WriteFile(fn, []byte(updateStationConfig(string(b), host, port)), 0644)
+}
+
+// serviceUnitReadsConfig checks whether given systemd unit file has environment file directive
+// referencing given station service configuration file
+func serviceUnitReadsConfig(unitFile, config string) (bool, error) {
+	b, err := ioutil.ReadFile(unitFile)
+	if err != nil {
+		return false, err
+	}
+	for _, l := range strings.Split(string(b), "\n") {
+		l = strings.TrimSpace(l)
+		if !strings.HasPrefix(l, "EnvironmentFile=") {
+			continue
+		}
+		for _, f := range strings.Fields(strings.TrimPrefix(l, "EnvironmentFile=")) {
+			if strings.TrimPrefix(f, "-") == config {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// updateStationConfig sets ESP station address options in station service environment file content
+func updateStationConfig(content, host string, port int) string {
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if content == "" {
+		lines = nil
+	}
+	found := false
+	for i, l := range lines {
+		l = strings.TrimSpace(l)
+		if !strings.HasPrefix(l, stationExecOptionsVar+"=") {
+			continue
+		}
+		options := strings.Trim(strings.TrimPrefix(l, stationExecOptionsVar+"="), `"'`)
+		lines[i] = fmt.Sprintf("%s=\"%s\"", stationExecOptionsVar, setStationAddressOptions(options, host, port))
+		found = true
+	}
+	if !found {
+		lines = append(lines, fmt.Sprintf("%s=\"%s\"", stationExecOptionsVar,
+			setStationAddressOptions("", host, port)))
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// setStationAddressOptions replaces station mode and address options in given command line options
+func setStationAddressOptions(options, host string, port int) string {
+	fields := strings.Fields(options)
+	var res []string
+	for i := 0; i < len(fields); i++ {
+		name := strings.TrimLeft(fields[i], "-")
+		if len(name) == len(fields[i]) {
+			res = append(res, fields[i])
+			continue
+		}
+		switch {
+		case name == "m" || name == "h" || name == "p":
+			// Skip option value too
+			i++
+		case strings.HasPrefix(name, "m=") || strings.HasPrefix(name, "h=") || strings.HasPrefix(name, "p="):
+		default:
+			res = append(res, fields[i])
+		}
+	}
+	res = append(res, "-h", host)
+	if port != 80 {
+		res = append(res, "-p", strconv.Itoa(port))
+	}
+	return strings.Join(res, " ")
+}
@@ -0,0 +1,94 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbeEspStation(t *testing.T) {
+	b, err := ioutil.ReadFile(filepath.Join("testdata", "esp-mega-20230623.json"))
+	require.NoError(t, err)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/json" {
+			_, _ = w.Write(b)
+		} else {
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	defer srv.Close()
+
+	host, p, err := net.SplitHostPort(srv.Listener.Addr().String())
+	require.NoError(t, err)
+	port, err := strconv.Atoi(p)
+	require.NoError(t, err)
+
+	ds, err := probeEspStation("OpenAir.local", host, port)
+	require.NoError(t, err)
+	require.Equal(t, "12:34:56:78:90:AB", ds.Mac)
+	require.Equal(t, stationTokenId("12:34:56:78:90:AB"), ds.TokenId)
+	require.Equal(t, "mega-20230623", ds.Firmware)
+	require.Equal(t, []string{"BME280", "SDS011"}, ds.Sensors)
+
+	stations := []*DiscoveredStation{ds}
+	require.Equal(t, ds, findDiscoveredStation(stations, "1"))
+	require.Equal(t, ds, findDiscoveredStation(stations, "openair"))
+	require.Nil(t, findDiscoveredStation(stations, "2"))
+
+	var buf bytes.Buffer
+	printDiscoveredStations(&buf, stations)
+	require.Contains(t, buf.String(), "OpenAir.local")
+	require.Contains(t, buf.String(), "BME280, SDS011")
+}
+
+func TestUpdateStationConfig(t *testing.T) {
+	require.Equal(t, "STATION_EXEC_OPTIONS=\"-h OpenAir-2.local\"\n",
+		updateStationConfig("", "OpenAir-2.local", 80))
+	require.Equal(t, "# options\nSTATION_EXEC_OPTIONS=\"-d -history /var/lib/openair -h unit.local -p 8080\"\n",
+		updateStationConfig("# options\nSTATION_EXEC_OPTIONS=\"-d -m esphome -h=old.local -p 81 "+
+			"-history /var/lib/openair\"\n", "unit.local", 8080))
+	require.True(t, strings.HasPrefix(updateStationConfig("OTHER=1", "unit.local", 80), "OTHER=1\n"))
+}
+
+func TestServiceUnitReadsConfig(t *testing.T) {
+	dir := t.TempDir()
+	unitFile := filepath.Join(dir, "openair-station.service")
+
+	_, err := serviceUnitReadsConfig(unitFile, stationConfigFile)
+	require.Error(t, err)
+
+	require.NoError(t, ioutil.WriteFile(unitFile, []byte("[Service]\n"+
+		"ExecStart=/usr/bin/openair-station $STATION_EXEC_OPTIONS\n"), 0644))
+	ok, err := serviceUnitReadsConfig(unitFile, stationConfigFile)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.NoError(t, ioutil.WriteFile(unitFile, []byte("[Service]\n"+
+		"EnvironmentFile=-/etc/default/openair-station\n"+
+		"ExecStart=/usr/bin/openair-station $STATION_EXEC_OPTIONS\n"), 0644))
+	ok, err = serviceUnitReadsConfig(unitFile, stationConfigFile)
+	require.NoError(t, err)
+	require.True(t, ok)
+}
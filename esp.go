@@ -15,11 +15,16 @@
 package main
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/openairtech/api"
 )
 
+// Oldest ESP Easy firmware release date with known JSON layout
+var espMinFirmwareReleaseDate = time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+
 type EspData struct {
 	System  *EspSystem   `json:"System,omitempty"`
 	WiFi    *EspWiFi     `json:"WiFi,omitempty"`
@@ -27,37 +32,54 @@ type EspData struct {
 	TTL     int          `json:"TTL,omitempty"`
 }
 
+// EspSystem is the ESP Easy system information,
+// JSON keys are matched case-insensitively so differently capitalized keys
+// of different firmware releases (like "Local time" and "Local Time") map to the same field
 type EspSystem struct {
 	Build             int     `json:"Build,omitempty"`
 	GitBuild          string  `json:"Git Build,omitempty"`
 	SystemLibraries   string  `json:"System libraries,omitempty"`
-	Plugins           int     `json:"Plugins,omitempty"`
+	Plugins           int     `json:"Plugins,omitempty"`      // mega-20190301
+	PluginCount       int     `json:"Plugin Count,omitempty"` // mega-20210503
 	PluginDescription string  `json:"Plugin description,omitempty"`
+	BuildTime         string  `json:"Build Time,omitempty"`
+	BinaryFilename    string  `json:"Binary Filename,omitempty"`
 	LocalTime         string  `json:"Local time,omitempty"`
-	Unit              int     `json:"Unit,omitempty"`
-	Name              string  `json:"Name,omitempty"`
-	UnitName          string  `json:"Unit Name,omitempty"`
+	TimeSource        string  `json:"Time Source,omitempty"`
+	Unit              int     `json:"Unit,omitempty"`        // mega-20190301
+	UnitNr            int     `json:"Unit Number,omitempty"` // mega-20190903
+	Name              string  `json:"Name,omitempty"`        // mega-20190301
+	UnitName          string  `json:"Unit Name,omitempty"`   // mega-20190903
 	Uptime            int     `json:"Uptime,omitempty"`
+	UptimeMs          int64   `json:"Uptime (ms),omitempty"` // mega-20230623
 	LastBootCause     string  `json:"Last boot cause,omitempty"`
 	ResetReason       string  `json:"Reset Reason,omitempty"`
 	Load              float32 `json:"Load,omitempty"`
 	LoadLC            int     `json:"Load LC,omitempty"`
 	FreeRAM           int     `json:"Free RAM,omitempty"`
+	FreeStack         int     `json:"Free Stack,omitempty"`
+	HeapMaxFreeBlock  int     `json:"Heap Max Free Block,omitempty"`
+	HeapFragmentation int     `json:"Heap Fragmentation,omitempty"`
+	ChipModel         string  `json:"ESP Chip Model,omitempty"`
 }
 
 type EspWiFi struct {
 	Hostname                string `json:"Hostname,omitempty"`
 	IPConfig                string `json:"IP config,omitempty"`
-	IP                      string `json:"IP,omitempty"`
-	SubnetMask              string `json:"Subnet Mask,omitempty"`
-	GatewayIP               string `json:"Gateway IP,omitempty"`
-	MACAddress              string `json:"MAC address"`       // mega-20190301
-	StationMAC              string `json:"STA MAC,omitempty"` // mega-20190903
+	IP                      string `json:"IP,omitempty"`          // mega-20190301
+	IPAddress               string `json:"IP Address,omitempty"`  // mega-20190903
+	SubnetMask              string `json:"Subnet Mask,omitempty"` // mega-20190301
+	IPSubnet                string `json:"IP Subnet,omitempty"`   // mega-20190903
+	GatewayIP               string `json:"Gateway IP,omitempty"`  // mega-20190301
+	Gateway                 string `json:"Gateway,omitempty"`     // mega-20190903
+	MACAddress              string `json:"MAC address"`           // mega-20190301
+	StationMAC              string `json:"STA MAC,omitempty"`     // mega-20190903
 	DNS1                    string `json:"DNS 1,omitempty"`
 	DNS2                    string `json:"DNS 2,omitempty"`
 	SSID                    string `json:"SSID,omitempty"`
 	BSSID                   string `json:"BSSID,omitempty"`
 	Channel                 int    `json:"Channel,omitempty"`
+	EncryptionType          string `json:"Encryption Type,omitempty"`
 	ConnectedMsec           int    `json:"Connected msec,omitempty"`
 	LastDisconnectReason    int    `json:"Last Disconnect Reason,omitempty"`
 	LastDisconnectReasonStr string `json:"Last Disconnect Reason str,omitempty"`
@@ -96,80 +118,96 @@ type EspGpioControlResponse struct {
 	State  int    `json:"state"`
 }
 
-func NewEspData(m *api.Measurement, uptime time.Duration, name string) *EspData {
-	bmeSensor := EspSensors{
-		TaskName: "BME280",
-		TaskValues: []EspTaskValues{
-			{
-				Name:  "Temperature",
-				Value: *m.Temperature,
-			},
-			{
-				Name:  "Humidity",
-				Value: *m.Humidity,
-			},
-			{
-				Name:  "Pressure",
-				Value: *m.Pressure,
-			},
-		},
+// NewEspData creates ESP Easy /json compatible document from given station data
+// that can be consumed by another station in ESP mode, absent sensor values are omitted
+func NewEspData(data *StationData) *EspData {
+	m := data.LastMeasurement
+
+	var sensors []EspSensors
+
+	var bmeValues []EspTaskValues
+	bmeValues = appendEspTaskValues(bmeValues, "Temperature", 1, m.Temperature)
+	bmeValues = appendEspTaskValues(bmeValues, "Humidity", 1, m.Humidity)
+	bmeValues = appendEspTaskValues(bmeValues, "Pressure", 1, m.Pressure)
+	if len(bmeValues) > 0 {
+		sensors = append(sensors, EspSensors{
+			TaskName:    "BME280",
+			Type:        "Environment - BMx280",
+			TaskEnabled: true,
+			TaskNumber:  len(sensors) + 1,
+			TaskValues:  bmeValues,
+		})
 	}
-	sdsSensor := EspSensors{
-		TaskName: "SDS011",
-		TaskValues: []EspTaskValues{
-			{
-				Name:  "PM2.5",
-				Value: *m.Pm25,
-			},
-			{
-				Name:  "PM10",
-				Value: *m.Pm10,
-			},
-		},
+
+	var sdsValues []EspTaskValues
+	sdsValues = appendEspTaskValues(sdsValues, "PM2.5", 1, m.Pm25)
+	sdsValues = appendEspTaskValues(sdsValues, "PM10", 1, m.Pm10)
+	if len(sdsValues) > 0 {
+		sensors = append(sensors, EspSensors{
+			TaskName:    "SDS011",
+			Type:        "Dust - SDS011/018/198",
+			TaskEnabled: true,
+			TaskNumber:  len(sensors) + 1,
+			TaskValues:  sdsValues,
+		})
 	}
+
 	return &EspData{
 		System: &EspSystem{
-			UnitName: name,
-			Uptime:   int(uptime.Minutes()),
+			UnitName: data.Version,
+			Uptime:   int(data.Uptime.Minutes()),
 		},
-		Sensors: []EspSensors{
-			bmeSensor,
-			sdsSensor,
+		WiFi: &EspWiFi{
+			MACAddress: data.MacAddress,
+			StationMAC: data.MacAddress,
 		},
+		Sensors: sensors,
 	}
 }
 
+// appendEspTaskValues appends referenced value rounded to the given number of decimal places
+// to task values if value is present
+func appendEspTaskValues(values []EspTaskValues, name string, decimals int, r *float32) []EspTaskValues {
+	if r == nil {
+		return values
+	}
+	return append(values, EspTaskValues{
+		ValueNumber: len(values) + 1,
+		Name:        name,
+		NrDecimals:  decimals,
+		Value:       Float32Round(*r, decimals),
+	})
+}
+
+var defaultEspMapping = DefaultEspMapping()
+
+// Measurement creates measurement from ESP Easy data using default task mapping
 func (ed *EspData) Measurement(t api.UnixTime) *api.Measurement {
+	return ed.MappedMeasurement(t, defaultEspMapping)
+}
+
+// MappedMeasurement creates measurement from enabled ESP Easy tasks values using given task mapping
+func (ed *EspData) MappedMeasurement(t api.UnixTime, mapping *EspMapping) *api.Measurement {
 	m := api.Measurement{
 		Timestamp: &t,
 	}
 
-	for _, s := range ed.Sensors {
-		if !s.TaskEnabled {
-			continue
-		}
-		switch s.TaskName {
-		case "BME280":
-			for _, v := range s.TaskValues {
-				cv := v
-				switch v.Name {
-				case "Temperature":
-					m.Temperature = &cv.Value
-				case "Humidity":
-					m.Humidity = &cv.Value
-				case "Pressure":
-					m.Pressure = &cv.Value
-				}
+	// Tasks mapped by plugin type don't override values of tasks mapped by name
+	mappings := mapping.Sensors(ed.Sensors)
+	for _, byName := range []bool{true, false} {
+		for i, sm := range mappings {
+			if sm == nil || (findSensorMapping(mapping.Tasks, ed.Sensors[i].TaskName) != nil) != byName {
+				continue
 			}
-		case "SDS011":
-			for _, v := range s.TaskValues {
-				cv := v
-				switch v.Name {
-				case "PM2.5":
-					m.Pm25 = &cv.Value
-				case "PM10":
-					m.Pm10 = &cv.Value
+			for _, v := range ed.Sensors[i].TaskValues {
+				vm := sm.Value(v.Name)
+				if vm == nil {
+					continue
+				}
+				if r := MeasurementFieldRef(&m, vm.Field); !byName && r != nil && *r != nil {
+					continue
 				}
+				_ = SetMeasurementField(&m, vm.Field, vm.Apply(v.Value))
 			}
 		}
 	}
@@ -177,7 +215,91 @@ func (ed *EspData) Measurement(t api.UnixTime) *api.Measurement {
 	return &m
 }
 
+// DeviceStatus returns ESP device system status
+func (ed *EspData) DeviceStatus() *DeviceStatus {
+	ds := &DeviceStatus{}
+	if es := ed.System; es != nil {
+		ds.Firmware = es.FirmwareVersion()
+		ds.ResetReason = es.ResetReason
+		if es.FreeRAM != 0 {
+			freeRam := es.FreeRAM
+			ds.FreeRam = &freeRam
+		}
+		if es.Load != 0 || es.LoadLC != 0 {
+			load := es.Load
+			ds.Load = &load
+		}
+	}
+	if ed.WiFi != nil && ed.WiFi.RSSI != 0 {
+		rssi := ed.WiFi.RSSI
+		ds.Rssi = &rssi
+	}
+	return ds
+}
+
+// FirmwareVersion returns ESP Easy firmware release name (like "mega-20230623")
+// or build number if release name is unknown
+func (es *EspSystem) FirmwareVersion() string {
+	if es.GitBuild != "" {
+		return es.GitBuild
+	}
+	if es.Build != 0 {
+		return fmt.Sprintf("build %d", es.Build)
+	}
+	return ""
+}
+
+// ReleaseDate returns ESP Easy firmware release date parsed from release name
+// or zero time if release date is unknown
+func (es *EspSystem) ReleaseDate() time.Time {
+	i := strings.LastIndex(es.GitBuild, "-")
+	if i < 0 {
+		return time.Time{}
+	}
+	t, err := time.Parse("20060102", SubString(es.GitBuild, i+1, 8))
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// UptimeDuration returns ESP system uptime with millisecond resolution
+// if supported by firmware or with minute resolution otherwise
+func (es *EspSystem) UptimeDuration() time.Duration {
+	if es.UptimeMs > 0 {
+		return time.Duration(es.UptimeMs) * time.Millisecond
+	}
+	return time.Duration(es.Uptime) * time.Minute
+}
+
+func (es *EspSystem) UnitNumber() int {
+	if es.UnitNr != 0 {
+		return es.UnitNr
+	}
+	return es.Unit
+}
+
+func (es *EspSystem) UnitDisplayName() string {
+	if es.UnitName != "" {
+		return es.UnitName
+	}
+	return es.Name
+}
+
+func (ew *EspWiFi) IpAddress() string {
+	if ew == nil {
+		return ""
+	}
+	if ew.IPAddress != "" {
+		return ew.IPAddress
+	}
+	return ew.IP
+}
+
 func (ew *EspWiFi) MacAddress() string {
+	if ew == nil {
+		return ""
+	}
 	if ew.MACAddress != "" {
 		return ew.MACAddress
 	}
@@ -16,8 +16,14 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -63,6 +69,8 @@ func TestEspData_Measurement(t *testing.T) {
 	}{
 		{name: "esp-mega-20190301", file: "esp-mega-20190301.json", want: m},
 		{name: "esp-mega-20190903", file: "esp-mega-20190903.json", want: m},
+		{name: "esp-mega-20210503", file: "esp-mega-20210503.json", want: m},
+		{name: "esp-mega-20230623", file: "esp-mega-20230623.json", want: m},
 	}
 
 	for _, tt := range tests {
@@ -83,6 +91,8 @@ func TestEspWiFi_MacAddress(t *testing.T) {
 	}{
 		{name: "esp-mega-20190301", file: "esp-mega-20190301.json", want: "12:34:56:78:90:AB"},
 		{name: "esp-mega-20190903", file: "esp-mega-20190903.json", want: "12:34:56:78:90:AB"},
+		{name: "esp-mega-20210503", file: "esp-mega-20210503.json", want: "12:34:56:78:90:AB"},
+		{name: "esp-mega-20230623", file: "esp-mega-20230623.json", want: "12:34:56:78:90:AB"},
 	}
 
 	for _, tt := range tests {
@@ -94,3 +104,257 @@ func TestEspWiFi_MacAddress(t *testing.T) {
 		})
 	}
 }
+
+func TestEspData_Layouts(t *testing.T) {
+	intRef := func(i int) *int { return &i }
+	float32Ref := func(f float32) *float32 { return &f }
+
+	tests := []struct {
+		file       string
+		firmware   string
+		unitName   string
+		ipAddress  string
+		uptime     time.Duration
+		deviceData DeviceStatus
+	}{
+		{file: "esp-mega-20190301.json", firmware: "build 20103", unitName: "OpenAir", ipAddress: "192.168.1.1",
+			uptime: 7238 * time.Minute, deviceData: DeviceStatus{Firmware: "build 20103", Rssi: intRef(-36),
+				FreeRam: intRef(11864), Load: float32Ref(20.6), ResetReason: "External System"}},
+		{file: "esp-mega-20190903.json", firmware: "build 20103", unitName: "OpenAir", ipAddress: "192.168.1.1",
+			uptime: 617 * time.Minute, deviceData: DeviceStatus{Firmware: "build 20103", Rssi: intRef(-64),
+				FreeRam: intRef(10584), Load: float32Ref(11), ResetReason: "Software/System restart"}},
+		{file: "esp-mega-20210503.json", firmware: "mega-20210503", unitName: "OpenAir", ipAddress: "192.168.1.1",
+			uptime: 617 * time.Minute, deviceData: DeviceStatus{Firmware: "mega-20210503", Rssi: intRef(-71),
+				FreeRam: intRef(17120), Load: float32Ref(9.41), ResetReason: "Software/System restart"}},
+		{file: "esp-mega-20230623.json", firmware: "mega-20230623", unitName: "OpenAir", ipAddress: "192.168.1.1",
+			uptime: 37042664 * time.Millisecond, deviceData: DeviceStatus{Firmware: "mega-20230623", Rssi: intRef(-58),
+				FreeRam: intRef(18328), Load: float32Ref(12.34), ResetReason: "Hardware Watchdog"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			ed, err := testReadEspData(tt.file)
+			require.NoError(t, err)
+			require.Equal(t, tt.firmware, ed.System.FirmwareVersion())
+			require.Equal(t, tt.unitName, ed.System.UnitDisplayName())
+			require.Equal(t, tt.ipAddress, ed.WiFi.IpAddress())
+			require.Equal(t, tt.uptime, ed.System.UptimeDuration())
+			require.Equal(t, tt.deviceData, *ed.DeviceStatus())
+		})
+	}
+}
+
+func TestEspSystem_ReleaseDate(t *testing.T) {
+	require.True(t, (&EspSystem{}).ReleaseDate().IsZero())
+	require.Equal(t, time.Date(2023, 6, 23, 0, 0, 0, 0, time.UTC),
+		(&EspSystem{GitBuild: "mega-20230623"}).ReleaseDate())
+}
+
+func TestNewEspData_RoundTrip(t *testing.T) {
+	ts := api.UnixTime(time.Unix(1600000000, 0))
+	temp := float32(25)
+	humidity := float32(33.5)
+	pressure := float32(1015.1)
+	pm25 := float32(1.8)
+	pm10 := float32(14.5)
+
+	m := api.Measurement{
+		Timestamp:   &ts,
+		Temperature: &temp,
+		Humidity:    &humidity,
+		Pressure:    &pressure,
+		Pm25:        &pm25,
+		Pm10:        &pm10,
+	}
+
+	macAddress := "12:34:56:78:90:AB"
+	data := &StationData{
+		Version:         "rpi-test",
+		TokenId:         stationTokenId(macAddress),
+		MacAddress:      macAddress,
+		Uptime:          90 * time.Minute,
+		LastMeasurement: &m,
+	}
+
+	b, err := json.Marshal(NewEspData(data))
+	require.NoError(t, err)
+
+	var ed EspData
+	require.NoError(t, json.Unmarshal(b, &ed))
+
+	require.Equal(t, m, *ed.Measurement(ts))
+	require.Equal(t, data.TokenId, stationTokenId(ed.WiFi.MacAddress()))
+	require.Equal(t, 90, ed.System.Uptime)
+}
+
+func TestNewEspData_PartialMeasurement(t *testing.T) {
+	ts := api.UnixTime(time.Unix(1600000000, 0))
+	temp := float32(25)
+	pm10 := float32(14.5)
+
+	tests := []struct {
+		name  string
+		m     api.Measurement
+		tasks []string
+	}{
+		{name: "no values", m: api.Measurement{Timestamp: &ts}},
+		{name: "no PM values", m: api.Measurement{Timestamp: &ts, Temperature: &temp}, tasks: []string{"BME280"}},
+		{name: "no environment values", m: api.Measurement{Timestamp: &ts, Pm10: &pm10}, tasks: []string{"SDS011"}},
+		{name: "partial values", m: api.Measurement{Timestamp: &ts, Temperature: &temp, Pm10: &pm10},
+			tasks: []string{"BME280", "SDS011"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ed := NewEspData(&StationData{LastMeasurement: &tt.m})
+			var tasks []string
+			for _, s := range ed.Sensors {
+				tasks = append(tasks, s.TaskName)
+			}
+			require.Equal(t, tt.tasks, tasks)
+			require.Equal(t, tt.m, *ed.Measurement(ts))
+		})
+	}
+}
+
+func TestEspData_MappedMeasurement(t *testing.T) {
+	mapping, err := LoadEspMapping(filepath.Join("testdata", "esp-mapping.json"))
+	require.NoError(t, err)
+
+	ed := EspData{
+		Sensors: []EspSensors{
+			{TaskName: "Env", Type: "Environment - BME680", TaskEnabled: true, TaskValues: []EspTaskValues{
+				{Name: "Temperature", Value: 21.5}, {Name: "Humidity", Value: 40}, {Name: "Gas", Value: 120}}},
+			{TaskName: "Dust", Type: "Dust - PMSx003", TaskEnabled: true, TaskValues: []EspTaskValues{
+				{Name: "pm1.0", Value: 3}, {Name: "pm2.5", Value: 5}, {Name: "pm10", Value: 8}}},
+			{TaskName: "Outdoor", Type: "Generic - Dummy Device", TaskEnabled: true, TaskValues: []EspTaskValues{
+				{Name: "Press", Value: 101325}}},
+			{TaskName: "Spare", Type: "Environment - SHT30/31/35", TaskEnabled: false, TaskValues: []EspTaskValues{
+				{Name: "Temperature", Value: 99}}},
+		},
+	}
+
+	ts := api.UnixTime(time.Now())
+	temp := float32(21.5)
+	humidity := float32(40)
+	pressure := float32(1013.25)
+	pm25 := float32(5)
+	pm10 := float32(8)
+
+	require.Equal(t, api.Measurement{
+		Timestamp:   &ts,
+		Temperature: &temp,
+		Humidity:    &humidity,
+		Pressure:    &pressure,
+		Pm25:        &pm25,
+		Pm10:        &pm10,
+	}, *ed.MappedMeasurement(ts, mapping))
+
+	// Renamed tasks aren't recognized by plugin type with default mapping
+	m := ed.Measurement(ts)
+	require.Nil(t, m.Temperature)
+	require.Nil(t, m.Pm10)
+
+	// Renamed tasks are recognized by plugin type if default plugins mapping is enabled
+	fn := filepath.Join(t.TempDir(), "esp-mapping.json")
+	require.NoError(t, ioutil.WriteFile(fn, []byte(`{"default_plugins": true}`), 0644))
+	mapping, err = LoadEspMapping(fn)
+	require.NoError(t, err)
+	m = ed.MappedMeasurement(ts, mapping)
+	require.Equal(t, &temp, m.Temperature)
+	require.Equal(t, &pm10, m.Pm10)
+	require.Nil(t, m.Pressure)
+
+	// Several tasks of the same plugin type are recognized by name only
+	bme := func(name string, temp float32) EspSensors {
+		return EspSensors{TaskName: name, Type: "Environment - BMx280", TaskEnabled: true,
+			TaskValues: []EspTaskValues{{Name: "Temperature", Value: temp}}}
+	}
+	ed = EspData{Sensors: []EspSensors{bme("Indoor", 24), bme("Outdoor", 21.5)}}
+	require.Nil(t, ed.MappedMeasurement(ts, mapping).Temperature)
+	ed = EspData{Sensors: []EspSensors{bme("Outdoor", 24), bme("BME280", 21.5)}}
+	require.Equal(t, &temp, ed.MappedMeasurement(ts, mapping).Temperature)
+
+	// Task mapped by plugin type doesn't override values of task mapped by name
+	dht := EspSensors{TaskName: "Indoor", Type: "Environment - DHT11/12/22  SONOFF2301/7021", TaskEnabled: true,
+		TaskValues: []EspTaskValues{{Name: "Temperature", Value: 24}, {Name: "Humidity", Value: 60}}}
+	ed = EspData{Sensors: []EspSensors{bme("BME280", 21.5), dht}}
+	m = ed.MappedMeasurement(ts, mapping)
+	require.Equal(t, &temp, m.Temperature)
+	require.Equal(t, float32(60), *m.Humidity)
+	require.Equal(t, &temp, ed.Measurement(ts).Temperature)
+	require.Nil(t, ed.Measurement(ts).Humidity)
+}
+
+func TestEspStation_Heater(t *testing.T) {
+	b, err := ioutil.ReadFile(filepath.Join("testdata", "esp-mega-20230623.json"))
+	require.NoError(t, err)
+
+	// ESP Easy device with heater pin which could be stuck in low state
+	pinState, stuck, failing, controlRequests := 1, false, false, 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/json", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(b)
+	})
+	mux.HandleFunc("/control", func(w http.ResponseWriter, r *http.Request) {
+		controlRequests++
+		cmd := strings.Split(r.URL.Query().Get("cmd"), ",")
+		if strings.EqualFold(cmd[0], "GPIO") && failing {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		if strings.EqualFold(cmd[0], "GPIO") && !stuck {
+			pinState, _ = strconv.Atoi(cmd[2])
+		}
+		_, _ = fmt.Fprintf(w, `{"log":"","plugin":1,"pin":14,"mode":"output","state":%d}`, pinState)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	host, p, err := net.SplitHostPort(srv.Listener.Addr().String())
+	require.NoError(t, err)
+	port, err := strconv.Atoi(p)
+	require.NoError(t, err)
+
+	// Heater pin isn't touched if heater is disabled
+	require.NoError(t, NewEspStation("test", host, port, -1, "", DefaultEspMapping()).Start())
+	require.Equal(t, 0, controlRequests)
+
+	es := NewEspStation("test", host, port, 14, "", DefaultEspMapping())
+	require.NoError(t, es.Start())
+	require.Equal(t, HeaterOn, es.HeaterState(), "actual pin state is read on start")
+
+	es.TurnHeater(HeaterOff)
+	require.Equal(t, HeaterOff, es.HeaterState())
+
+	stuck = true
+	es.TurnHeater(HeaterOn)
+	require.Equal(t, HeaterOff, es.HeaterState(), "actual pin state is kept on mismatch")
+
+	// Pin state changed by device is reconciled with requested state
+	stuck = false
+	es.TurnHeater(HeaterOn)
+	pinState = 0
+	_, err = es.GetData()
+	require.NoError(t, err)
+	require.Equal(t, 0, pinState, "reconcile is not due yet")
+	es.heaterReconcileTime = time.Now().Add(-espHeaterReconcileInterval)
+	_, err = es.GetData()
+	require.NoError(t, err)
+	require.Equal(t, 1, pinState)
+	require.Equal(t, HeaterOn, es.HeaterState())
+
+	// Requested state is kept until pin state is set successfully
+	pinState = 0
+	es.heaterReconcileTime = time.Now().Add(-espHeaterReconcileInterval)
+	failing = true
+	_, err = es.GetData()
+	require.NoError(t, err)
+	require.Equal(t, HeaterOff, es.HeaterState(), "pin state can't be set")
+	failing = false
+	es.heaterReconcileTime = time.Now().Add(-espHeaterReconcileInterval)
+	_, err = es.GetData()
+	require.NoError(t, err)
+	require.Equal(t, 1, pinState)
+	require.Equal(t, HeaterOn, es.HeaterState())
+}
@@ -0,0 +1,324 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/openairtech/api"
+)
+
+const (
+	// ESPHome web server event stream reconnection delay
+	espHomeEventsReconnectDelay = 10 * time.Second
+	// ESPHome web server event stream inactivity timeout (web server sends ping events periodically)
+	espHomeEventsTimeout = time.Minute
+)
+
+// EspHomeEntityState is the ESPHome web server entity state
+// https://esphome.io/web-api/index.html
+type EspHomeEntityState struct {
+	Id    string      `json:"id"`
+	Value interface{} `json:"value"`
+	State string      `json:"state"`
+}
+
+// EspHomeStation reads sensor states from ESPHome device web server event stream
+// (falling back to REST API requests while event stream is not connected)
+// and controls heater switch using REST API
+type EspHomeStation struct {
+	sync.Mutex
+
+	version string
+
+	host string
+	port int
+
+	tokenId string
+
+	// Measurement field to sensor object ID mapping
+	sensors      map[string]string
+	uptimeSensor string
+	macSensor    string
+	macAddress   string
+
+	heaterSwitch string
+	heaterState  HeaterState
+
+	startTime  time.Time
+	lastUptime *time.Duration
+
+	// Entity states received from event stream by event entity ID (nil if stream is not connected)
+	states       map[string]EspHomeEntityState
+	eventsCancel context.CancelFunc
+	eventsWg     sync.WaitGroup
+}
+
+func NewEspHomeStation(version, host string, port int, sensors map[string]string, uptimeSensor, macSensor,
+	heaterSwitch, tokenId string) *EspHomeStation {
+	return &EspHomeStation{
+		version:      version,
+		host:         host,
+		port:         port,
+		tokenId:      tokenId,
+		sensors:      sensors,
+		uptimeSensor: uptimeSensor,
+		macSensor:    macSensor,
+		heaterSwitch: heaterSwitch,
+	}
+}
+
+func (ehs *EspHomeStation) Version() string {
+	return ehs.version
+}
+
+func (ehs *EspHomeStation) Start() error {
+	ehs.startTime = time.Now()
+	var ctx context.Context
+	ctx, ehs.eventsCancel = context.WithCancel(context.Background())
+	ehs.eventsWg.Add(1)
+	go ehs.readEvents(ctx)
+	log.Print("started ESPHome station")
+	return nil
+}
+
+func (ehs *EspHomeStation) Stop() {
+	if ehs.eventsCancel != nil {
+		ehs.eventsCancel()
+		ehs.eventsWg.Wait()
+	}
+	log.Print("stopped ESPHome station")
+}
+
+func (ehs *EspHomeStation) HeaterState() HeaterState {
+	return ehs.heaterState
+}
+
+func (ehs *EspHomeStation) TurnHeater(state HeaterState) {
+	if ehs.heaterSwitch == "" {
+		log.Error("can't turn heater: ESPHome heater switch is not set")
+		return
+	}
+
+	action := "turn_off"
+	if state == HeaterOn {
+		action = "turn_on"
+	}
+
+	u := fmt.Sprintf("%s/%s", ehs.entityUrl("switch", ehs.heaterSwitch), action)
+	if _, err := HttpPostData(u, nil, nil); err != nil {
+		log.Errorf("can't %s heater switch %s: %v", action, ehs.heaterSwitch, err)
+		return
+	}
+
+	ehs.heaterState = state
+
+	if state == HeaterOn {
+		log.Debug("heater turned on")
+	} else {
+		log.Debug("heater turned off")
+	}
+}
+
+func (ehs *EspHomeStation) GetData() (*StationData, error) {
+	log.Debugf("getting sensor data from ESPHome station %s:%d", ehs.host, ehs.port)
+
+	m := &api.Measurement{}
+	timestamp := api.UnixTime(time.Now())
+	m.Timestamp = &timestamp
+
+	for field, id := range ehs.sensors {
+		v, err := ehs.sensorValue(id)
+		if err != nil {
+			log.Errorf("sensor %s data request failed: %v", id, err)
+			return nil, err
+		}
+		if v == nil {
+			log.Debugf("sensor %s has no state", id)
+			continue
+		}
+		_ = SetMeasurementField(m, field, *v)
+	}
+
+	if ehs.macAddress == "" && ehs.macSensor != "" {
+		s, err := ehs.entityState("text_sensor", ehs.macSensor)
+		if err != nil {
+			log.Errorf("MAC address text sensor %s request failed: %v", ehs.macSensor, err)
+		} else if s == nil {
+			log.Errorf("MAC address text sensor %s not found", ehs.macSensor)
+		} else {
+			ehs.macAddress = s.State
+			log.Debugf("MAC address: %s", ehs.macAddress)
+		}
+	}
+
+	tokenId := ehs.tokenId
+	if tokenId == "" {
+		if ehs.macAddress == "" {
+			return nil, errors.New("can't determine ESPHome station MAC address")
+		}
+		tokenId = stationTokenId(ehs.macAddress)
+	}
+	log.Debugf("token ID: %s", tokenId)
+
+	uptime := time.Since(ehs.startTime)
+	if ehs.uptimeSensor != "" {
+		v, err := ehs.sensorValue(ehs.uptimeSensor)
+		if err != nil {
+			log.Errorf("uptime sensor %s request failed: %v", ehs.uptimeSensor, err)
+			return nil, err
+		}
+		if v != nil {
+			uptime = time.Duration(*v) * time.Second
+			if ehs.lastUptime != nil && uptime < *ehs.lastUptime {
+				log.Warn("ESPHome station reboot detected")
+				ehs.heaterState = HeaterOff
+			}
+			ehs.lastUptime = &uptime
+		}
+	}
+
+	return &StationData{
+		Version:         ehs.version,
+		TokenId:         tokenId,
+		MacAddress:      ehs.macAddress,
+		Uptime:          uptime,
+		LastMeasurement: m,
+	}, nil
+}
+
+// sensorValue returns sensor numeric state or nil if sensor has no state or doesn't exist
+func (ehs *EspHomeStation) sensorValue(id string) (*float32, error) {
+	s, err := ehs.entityState("sensor", id)
+	if err != nil || s == nil {
+		return nil, err
+	}
+	if v, ok := s.Value.(float64); ok {
+		fv := float32(v)
+		return &fv, nil
+	}
+	return nil, nil
+}
+
+// entityState returns entity state received from event stream or requested using REST API
+// or nil if entity doesn't exist
+func (ehs *EspHomeStation) entityState(domain, id string) (*EspHomeEntityState, error) {
+	ehs.Lock()
+	s, ok := ehs.states[domain+"-"+id]
+	ehs.Unlock()
+	if ok {
+		return &s, nil
+	}
+
+	if err := HttpGetData(ehs.entityUrl(domain, id), &s); err != nil {
+		if he, ok := err.(*HttpError); ok && he.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+// readEvents keeps entity states received from web server event stream until given context is done
+func (ehs *EspHomeStation) readEvents(ctx context.Context) {
+	defer ehs.eventsWg.Done()
+	for {
+		if err := ehs.streamEvents(ctx); err != nil && ctx.Err() == nil {
+			log.Debugf("ESPHome event stream failed: %v", err)
+		}
+
+		ehs.Lock()
+		ehs.states = nil
+		ehs.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(espHomeEventsReconnectDelay):
+		}
+	}
+}
+
+func (ehs *EspHomeStation) streamEvents(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		fmt.Sprintf("http://%s:%d/events", ehs.host, ehs.port), nil)
+	if err != nil {
+		return err
+	}
+	// Shared HTTP client timeout would break long-lived event stream
+	r, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer CloseQuietly(r.Body)
+
+	if r.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status: %d", r.StatusCode)
+	}
+
+	log.Debugf("connected to ESPHome event stream")
+
+	ehs.Lock()
+	ehs.states = make(map[string]EspHomeEntityState)
+	ehs.Unlock()
+
+	// Drop stalled connection
+	timer := time.AfterFunc(espHomeEventsTimeout, cancel)
+	defer timer.Stop()
+
+	var event string
+	sc := bufio.NewScanner(r.Body)
+	for sc.Scan() {
+		timer.Reset(espHomeEventsTimeout)
+		line := sc.Text()
+		switch {
+		case line == "":
+			event = ""
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:") && event == "state":
+			var s EspHomeEntityState
+			if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "data:"))), &s); err != nil {
+				log.Debugf("can't parse ESPHome state event: %v", err)
+				continue
+			}
+			ehs.Lock()
+			ehs.states[s.Id] = s
+			ehs.Unlock()
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	return errors.New("event stream closed")
+}
+
+func (ehs *EspHomeStation) entityUrl(domain, id string) string {
+	return fmt.Sprintf("http://%s:%d/%s/%s", ehs.host, ehs.port, domain, url.PathEscape(id))
+}
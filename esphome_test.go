@@ -0,0 +1,131 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEspHomeStation(t *testing.T) {
+	var switchRequests []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sensor/", func(w http.ResponseWriter, r *http.Request) {
+		states := map[string]string{
+			"/sensor/bme_temperature": `{"id":"sensor-bme_temperature","value":21.5,"state":"21.5 °C"}`,
+			"/sensor/bme_humidity":    `{"id":"sensor-bme_humidity","value":45,"state":"45.0 %"}`,
+			"/sensor/pm_2_5":          `{"id":"sensor-pm_2_5","value":null,"state":"NA"}`,
+			"/sensor/uptime":          `{"id":"sensor-uptime","value":3600,"state":"3600 s"}`,
+		}
+		s, ok := states[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(s))
+	})
+	mux.HandleFunc("/text_sensor/mac_address", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id":"text_sensor-mac_address","value":"A4:CF:12:00:11:22","state":"A4:CF:12:00:11:22"}`))
+	})
+	mux.HandleFunc("/switch/heater/", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		switchRequests = append(switchRequests, r.URL.Path)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	host, p, err := net.SplitHostPort(srv.Listener.Addr().String())
+	require.NoError(t, err)
+	port, err := strconv.Atoi(p)
+	require.NoError(t, err)
+
+	sensors := map[string]string{
+		FieldTemperature: "bme_temperature",
+		FieldHumidity:    "bme_humidity",
+		FieldPm25:        "pm_2_5",
+		// Missing entity
+		FieldPressure: "bme_pressure",
+	}
+	ehs := NewEspHomeStation("test", host, port, sensors, "uptime", "mac_address", "heater", "")
+	require.NoError(t, ehs.Start())
+	defer ehs.Stop()
+
+	data, err := ehs.GetData()
+	require.NoError(t, err)
+	require.Equal(t, "A4:CF:12:00:11:22", data.MacAddress)
+	require.Equal(t, stationTokenId("A4:CF:12:00:11:22"), data.TokenId)
+	require.Equal(t, 3600.0, data.Uptime.Seconds())
+
+	m := data.LastMeasurement
+	require.NotNil(t, m.Temperature)
+	require.Equal(t, float32(21.5), *m.Temperature)
+	require.NotNil(t, m.Humidity)
+	require.Equal(t, float32(45), *m.Humidity)
+	require.Nil(t, m.Pm25)
+	require.Nil(t, m.Pressure)
+
+	ehs.TurnHeater(HeaterOn)
+	require.Equal(t, HeaterOn, ehs.HeaterState())
+	ehs.TurnHeater(HeaterOff)
+	require.Equal(t, HeaterOff, ehs.HeaterState())
+	require.Equal(t, []string{"/switch/heater/turn_on", "/switch/heater/turn_off"}, switchRequests)
+}
+
+func TestEspHomeStation_Events(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, e := range []string{
+			`event: ping` + "\n" + `data: {"title":"test"}`,
+			`event: state` + "\n" + `data: {"id":"sensor-pm_2_5","value":12.5,"state":"12.5 µg/m³"}`,
+			`event: state` + "\n" + `data: {"id":"text_sensor-mac_address","state":"A4:CF:12:00:11:22"}`,
+		} {
+			_, _ = fmt.Fprintf(w, "%s\n\n", e)
+		}
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+	// REST API is used only for entities without streamed state
+	mux.HandleFunc("/sensor/", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unexpected request", http.StatusInternalServerError)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	host, p, err := net.SplitHostPort(srv.Listener.Addr().String())
+	require.NoError(t, err)
+	port, err := strconv.Atoi(p)
+	require.NoError(t, err)
+
+	ehs := NewEspHomeStation("test", host, port, map[string]string{FieldPm25: "pm_2_5"}, "", "mac_address",
+		"", "")
+	require.NoError(t, ehs.Start())
+	defer ehs.Stop()
+
+	var data *StationData
+	require.Eventually(t, func() bool {
+		data, err = ehs.GetData()
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, stationTokenId("A4:CF:12:00:11:22"), data.TokenId)
+	require.Equal(t, float32(12.5), *data.LastMeasurement.Pm25)
+}
@@ -17,37 +17,132 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	log "github.com/sirupsen/logrus"
+
 	"github.com/openairtech/api"
 )
 
 const (
 	// Max feeder error length to log without truncating
 	maxFeederErrorLogLength = 255
+	// Luftdaten API PM sensor pin number
+	luftdatenPmSensorPin = 1
+	// Luftdaten API temperature/humidity/pressure sensor pin number
+	luftdatenEnvSensorPin = 11
 )
 
 type Feeder interface {
 	Feed(data *StationData)
+	Status() FeederStatus
+}
+
+// FeederStatus is the feeder data posting status
+type FeederStatus struct {
+	Name        string     `json:"name"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	LastFailure *time.Time `json:"last_failure,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+}
+
+// feederStatus tracks feeder data posting status
+type feederStatus struct {
+	sync.Mutex
+	status FeederStatus
+}
+
+func newFeederStatus(name string) feederStatus {
+	return feederStatus{status: FeederStatus{Name: name}}
+}
+
+func (fs *feederStatus) success() {
+	fs.Lock()
+	defer fs.Unlock()
+	now := time.Now()
+	fs.status.LastSuccess = &now
+}
+
+func (fs *feederStatus) failure(err error) {
+	fs.Lock()
+	defer fs.Unlock()
+	now := time.Now()
+	fs.status.LastFailure = &now
+	fs.status.LastError = TruncateString(err.Error(), maxFeederErrorLogLength)
+}
+
+func (fs *feederStatus) get() FeederStatus {
+	fs.Lock()
+	defer fs.Unlock()
+	return fs.status
 }
 
 // OpenAirFeeder feeds measurement data to OpenAir project server
 // https://github.com/openairtech/api
 type OpenAirFeeder struct {
+	sync.Mutex
+
 	apiServerUrl             string
 	measurementsKeepDuration time.Duration
 
 	measurements []api.Measurement
+
+	history           *History
+	backfillBatchSize int
+	backfillInterval  time.Duration
+	// Quality flags of measurements not to be backfilled
+	qualityDrop []string
+	// PM value average fed instead of instantaneous values
+	feedAverage string
+
+	state       openAirFeederState
+	backfilling bool
+	stop        chan struct{}
+
+	status feederStatus
+}
+
+// openAirFeederState is the persisted OpenAir feeder backfill state
+type openAirFeederState struct {
+	// Unix time of the newest measurement all measurements before which were acknowledged by server
+	HighWaterMark int64 `json:"hwm"`
+	// Unix time of the newest acknowledged measurement
+	Acknowledged int64 `json:"ack"`
+	// Unix time of the oldest measurement acknowledged after the gap being backfilled (0 if no gap)
+	BackfillUntil int64 `json:"backfill_until,omitempty"`
+	// Gaps detected while backfilling previous gap
+	Gaps []openAirFeederGap `json:"gaps,omitempty"`
+}
+
+// openAirFeederGap is the gap in posted measurements
+type openAirFeederGap struct {
+	// Unix time of the newest measurement acknowledged before the gap
+	After int64 `json:"after"`
+	// Unix time of the oldest measurement acknowledged after the gap
+	Until int64 `json:"until"`
 }
 
-func NewOpenAirFeeder(apiServerUrl string, measurementsKeepDuration time.Duration) *OpenAirFeeder {
-	return &OpenAirFeeder{
+func NewOpenAirFeeder(apiServerUrl string, measurementsKeepDuration time.Duration, history *History,
+	backfillBatchSize int, backfillInterval time.Duration) *OpenAirFeeder {
+	oaf := &OpenAirFeeder{
 		apiServerUrl:             apiServerUrl,
 		measurementsKeepDuration: measurementsKeepDuration,
+		history:                  history,
+		backfillBatchSize:        backfillBatchSize,
+		backfillInterval:         backfillInterval,
+		stop:                     make(chan struct{}),
+		status:                   newFeederStatus(FeederOpenAir),
 	}
+	if history != nil {
+		oaf.loadState()
+	}
+	return oaf
 }
 
 func (oaf *OpenAirFeeder) Feed(data *StationData) {
@@ -71,29 +166,280 @@ func (oaf *OpenAirFeeder) Feed(data *StationData) {
 	// Add last data measurement to buffered measurements
 	oaf.measurements = append(oaf.measurements, *data.LastMeasurement)
 
+	if err := oaf.post(data.TokenId, data.Version, oaf.measurements); err != nil {
+		oaf.status.failure(err)
+		return
+	}
+
+	oaf.status.success()
+
+	if oaf.history != nil {
+		oaf.acknowledged(data.TokenId, data.Version, oaf.measurements)
+	}
+
+	// Delete successfully posted buffered measurements
+	oaf.measurements = nil
+}
+
+func (oaf *OpenAirFeeder) Status() FeederStatus {
+	return oaf.status.get()
+}
+
+// SetQualityDrop sets quality flags of history measurements to skip on backfill
+func (oaf *OpenAirFeeder) SetQualityDrop(flags []string) {
+	oaf.Lock()
+	defer oaf.Unlock()
+	oaf.qualityDrop = flags
+}
+
+// SetFeedAverage sets PM value average to backfill instead of instantaneous history values
+func (oaf *OpenAirFeeder) SetFeedAverage(average string) {
+	oaf.Lock()
+	defer oaf.Unlock()
+	oaf.feedAverage = average
+}
+
+// Stop stops measurements backfill
+func (oaf *OpenAirFeeder) Stop() {
+	close(oaf.stop)
+}
+
+func (oaf *OpenAirFeeder) post(tokenId, version string, measurements []api.Measurement) error {
 	f := api.FeederData{
-		TokenId:      data.TokenId,
-		Version:      data.Version,
-		Measurements: oaf.measurements,
+		TokenId:      tokenId,
+		Version:      version,
+		Measurements: measurements,
 	}
 
-	log.Debugf("[OpenAir] posting %d measurement(s) to %s", len(oaf.measurements), oaf.apiServerUrl)
+	log.Debugf("[OpenAir] posting %d measurement(s) to %s", len(measurements), oaf.apiServerUrl)
 
 	var r api.Result
 	if err := HttpPostJson(oaf.apiServerUrl, nil, f, &r); err != nil {
 		log.Errorf("[OpenAir] data posting failed: %s",
 			TruncateString(err.Error(), maxFeederErrorLogLength))
-		return
+		return err
 	}
 	if r.Status != api.StatusOk {
 		log.Errorf("[OpenAir] data posting error: %d: %s", r.Status, r.Message)
+		return fmt.Errorf("data posting error: %d: %s", r.Status, r.Message)
+	}
+
+	log.Debugf("[OpenAir] successfully posted %d measurement(s) to %s", len(measurements), oaf.apiServerUrl)
+
+	return nil
+}
+
+// acknowledged updates backfill state after successful posting of given measurements
+// and starts backfilling if there is a gap between them and the high-water mark
+func (oaf *OpenAirFeeder) acknowledged(tokenId, version string, measurements []api.Measurement) {
+	oldest, newest := measurementsTimeRange(measurements)
+	if newest == 0 {
 		return
 	}
 
-	log.Debugf("[OpenAir] successfully posted %d measurement(s) to %s", len(oaf.measurements), oaf.apiServerUrl)
+	oaf.Lock()
+	defer oaf.Unlock()
 
-	// Delete successfully posted buffered measurements
-	oaf.measurements = nil
+	acknowledged := oaf.state.Acknowledged
+	if newest > oaf.state.Acknowledged {
+		oaf.state.Acknowledged = newest
+	}
+
+	if oaf.state.BackfillUntil == 0 {
+		if oaf.state.HighWaterMark != 0 && oldest > oaf.state.HighWaterMark &&
+			oaf.hasGap(oaf.state.HighWaterMark, oldest) {
+			oaf.state.BackfillUntil = oldest
+		}
+		if oaf.state.BackfillUntil == 0 {
+			oaf.state.HighWaterMark = oaf.state.Acknowledged
+		}
+	} else if oldest > acknowledged && oaf.hasGap(acknowledged, oldest) {
+		// Gap after the gap being backfilled is backfilled next
+		oaf.state.Gaps = append(oaf.state.Gaps, openAirFeederGap{After: acknowledged, Until: oldest})
+	}
+
+	oaf.saveState()
+
+	if oaf.state.BackfillUntil != 0 && !oaf.backfilling {
+		oaf.backfilling = true
+		go oaf.backfill(tokenId, version)
+	}
+}
+
+// hasGap checks whether there are history measurements between given Unix times
+func (oaf *OpenAirFeeder) hasGap(after, before int64) bool {
+	gap, err := oaf.history.Records(time.Unix(after, 0), time.Unix(before, 0), 1)
+	if err != nil {
+		log.Errorf("[OpenAir] can't read measurements history: %v", err)
+		return false
+	}
+	if len(gap) == 0 {
+		return false
+	}
+	log.Infof("[OpenAir] detected gap in posted measurements from %v to %v", time.Unix(after, 0),
+		time.Unix(before, 0))
+	return true
+}
+
+// backfill posts historical measurements missing on server in batches
+// with backfill interval between batches until the gap is filled, posting fails or feeder is stopped
+func (oaf *OpenAirFeeder) backfill(tokenId, version string) {
+	defer func() {
+		oaf.Lock()
+		oaf.backfilling = false
+		oaf.Unlock()
+	}()
+
+	averages, err := oaf.backfillAverages()
+	if err != nil {
+		log.Errorf("[OpenAir] can't start measurements backfill: %v", err)
+		return
+	}
+
+	for {
+		oaf.Lock()
+		after, before := time.Unix(oaf.state.HighWaterMark, 0), time.Unix(oaf.state.BackfillUntil, 0)
+		oaf.Unlock()
+
+		records, err := oaf.history.Records(after, before, oaf.backfillBatchSize)
+		if err != nil {
+			log.Errorf("[OpenAir] can't read measurements history: %v", err)
+			return
+		}
+
+		if len(records) == 0 {
+			oaf.Lock()
+			next := len(oaf.state.Gaps) > 0
+			if next {
+				oaf.state.HighWaterMark, oaf.state.BackfillUntil = oaf.state.Gaps[0].After, oaf.state.Gaps[0].Until
+				oaf.state.Gaps = oaf.state.Gaps[1:]
+			} else {
+				oaf.state.HighWaterMark = oaf.state.Acknowledged
+				oaf.state.BackfillUntil, oaf.state.Gaps = 0, nil
+			}
+			oaf.saveState()
+			oaf.Unlock()
+			if !next {
+				log.Info("[OpenAir] measurements backfill completed")
+				return
+			}
+			if averages, err = oaf.backfillAverages(); err != nil {
+				log.Errorf("[OpenAir] can't continue measurements backfill: %v", err)
+				return
+			}
+			continue
+		}
+
+		oaf.Lock()
+		drop := oaf.qualityDrop
+		oaf.Unlock()
+
+		var measurements []api.Measurement
+		for i := range records {
+			r := &records[i]
+			// Fed PM values are averaged the same way as for live posting
+			m := &r.Measurement
+			if averages != nil {
+				averages.Add(m)
+				m = averages.FeedData(&StationData{LastMeasurement: m,
+					Averages: averages.Status(r.Time())}).LastMeasurement
+			}
+			if f := DroppedQualityFlag(r.Quality, drop); f != nil {
+				log.Debugf("[OpenAir] skip backfilling measurement at %v: %s", r.Time(), f.Message)
+				continue
+			}
+			measurements = append(measurements, *m)
+		}
+
+		if len(measurements) > 0 {
+			log.Infof("[OpenAir] backfilling %d measurement(s) from %v", len(measurements),
+				time.Time(*measurements[0].Timestamp))
+
+			if err := oaf.post(tokenId, version, measurements); err != nil {
+				log.Warn("[OpenAir] measurements backfill suspended")
+				return
+			}
+		}
+
+		oaf.Lock()
+		oaf.state.HighWaterMark = records[len(records)-1].Time().Unix()
+		oaf.saveState()
+		oaf.Unlock()
+
+		select {
+		case <-oaf.stop:
+			log.Info("[OpenAir] measurements backfill stopped")
+			return
+		case <-time.After(oaf.backfillInterval):
+		}
+	}
+}
+
+// backfillAverages returns PM averages seeded with history measurements preceding the high-water mark
+// or nil if averages are not fed
+func (oaf *OpenAirFeeder) backfillAverages() (*PmAverages, error) {
+	oaf.Lock()
+	average, hwm := oaf.feedAverage, time.Unix(oaf.state.HighWaterMark, 0)
+	oaf.Unlock()
+
+	if average == "" || average == FeedAverageNone {
+		return nil, nil
+	}
+	pa, err := NewPmAverages(average)
+	if err != nil {
+		return nil, err
+	}
+	records, err := oaf.history.Records(hwm.Add(-pmAveragesMaxWindow), hwm.Add(time.Second), 0)
+	if err != nil {
+		return nil, err
+	}
+	pa.Seed(records)
+	return pa, nil
+}
+
+func (oaf *OpenAirFeeder) stateFileName() string {
+	return filepath.Join(oaf.history.Dir(), "openair-feeder.json")
+}
+
+func (oaf *OpenAirFeeder) loadState() {
+	b, err := ioutil.ReadFile(oaf.stateFileName())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Errorf("[OpenAir] can't read feeder state: %v", err)
+		}
+		return
+	}
+	if err := json.Unmarshal(b, &oaf.state); err != nil {
+		log.Errorf("[OpenAir] can't parse feeder state: %v", err)
+	}
+}
+
+func (oaf *OpenAirFeeder) saveState() {
+	b, err := json.Marshal(oaf.state)
+	if err != nil {
+		log.Errorf("[OpenAir] can't marshal feeder state: %v", err)
+		return
+	}
+	if err := ioutil.WriteFile(oaf.stateFileName(), b, 0644); err != nil {
+		log.Errorf("[OpenAir] can't save feeder state: %v", err)
+	}
+}
+
+// measurementsTimeRange returns Unix times of the oldest and the newest of given measurements
+func measurementsTimeRange(measurements []api.Measurement) (oldest, newest int64) {
+	for _, m := range measurements {
+		if m.Timestamp == nil {
+			continue
+		}
+		t := time.Time(*m.Timestamp).Unix()
+		if oldest == 0 || t < oldest {
+			oldest = t
+		}
+		if t > newest {
+			newest = t
+		}
+	}
+	return
 }
 
 type SensorDataValue struct {
@@ -103,9 +449,36 @@ type SensorDataValue struct {
 
 type SensorData struct {
 	SoftwareVersion  string            `json:"software_version"`
+	Age              string            `json:"age,omitempty"`
 	SensorDataValues []SensorDataValue `json:"sensordatavalues"`
 }
 
+// NewAirrohrSensorData creates airrohr firmware compatible sensor data
+// with value types prefixed by sensor names
+func NewAirrohrSensorData(data *StationData) *SensorData {
+	m := data.LastMeasurement
+	values := []SensorDataValue{}
+	values = appendSensorDataValue(values, "SDS_P1", m.Pm10, 1, 1)
+	values = appendSensorDataValue(values, "SDS_P2", m.Pm25, 1, 1)
+	values = appendSensorDataValue(values, "BME280_temperature", m.Temperature, 1, 1)
+	values = appendSensorDataValue(values, "BME280_humidity", m.Humidity, 1, 1)
+	values = appendSensorDataValue(values, "BME280_pressure", m.Pressure, 2, 100)
+	return &SensorData{
+		SoftwareVersion:  data.Version,
+		SensorDataValues: values,
+	}
+}
+
+// appendSensorDataValue appends referenced value rounded to the given number of decimal places
+// and then scaled to sensor data values, absent values are skipped
+func appendSensorDataValue(values []SensorDataValue, valueType string, r *float32, places int,
+	scale float32) []SensorDataValue {
+	if r == nil {
+		return values
+	}
+	return append(values, SensorDataValue{ValueType: valueType, Value: scale * Float32Round(*r, places)})
+}
+
 // LuftdatenFeeder feeds measurement data to Luftdaten (now Sensor.community) project server
 // https://github.com/opendata-stuttgart/meta/wiki/APIs
 // https://github.com/opendata-stuttgart/sensors-software/blob/master/airrohr-firmware/airrohr-firmware.ino
@@ -114,15 +487,22 @@ type LuftdatenFeeder struct {
 	sensorDataPostInterval time.Duration
 
 	lastSensorDataPostTime time.Time
+
+	status feederStatus
 }
 
 func NewLuftdatenFeeder() *LuftdatenFeeder {
 	return &LuftdatenFeeder{
 		apiServerUrl:           "https://api.luftdaten.info/v1/push-sensor-data/",
 		sensorDataPostInterval: 3 * time.Minute,
+		status:                 newFeederStatus(FeederLuftdaten),
 	}
 }
 
+func (lf *LuftdatenFeeder) Status() FeederStatus {
+	return lf.status.get()
+}
+
 func (lf *LuftdatenFeeder) Feed(data *StationData) {
 	numSensorId := data.TokenId[:12]
 	sensorId := fmt.Sprintf("raspi-%s", numSensorId)
@@ -134,35 +514,76 @@ func (lf *LuftdatenFeeder) Feed(data *StationData) {
 
 	lf.lastSensorDataPostTime = time.Now()
 
-	pmSensorData := &SensorData{
-		SoftwareVersion: data.Version,
-		SensorDataValues: []SensorDataValue{
-			{ValueType: "P1", Value: Float32RefRound(data.LastMeasurement.Pm10, 1)},
-			{ValueType: "P2", Value: Float32RefRound(data.LastMeasurement.Pm25, 1)},
-		},
+	pmSensorData, envSensorData := luftdatenSensorData(data)
+	if pmSensorData == nil && envSensorData == nil {
+		log.Debugf("[Luftdaten] %s: no sensor data to post", sensorId)
+		return
 	}
-	if err := lf.postSensorData(sensorId, 1, pmSensorData); err != nil {
-		if httpError, ok := err.(*HttpError); ok {
-			if httpError.StatusCode == 403 {
-				log.Infof("[Luftdaten] please register your station "+
-					"at https://devices.sensor.community/sensors/register "+
-					"(Sensor ID: %s, Sensor Board: raspi, Sensor Types: SDS011/BME280)",
-					numSensorId)
-				return
+
+	var failed bool
+
+	if pmSensorData != nil {
+		if err := lf.postSensorData(sensorId, luftdatenPmSensorPin, pmSensorData); err != nil {
+			lf.status.failure(err)
+			failed = true
+			if httpError, ok := err.(*HttpError); ok {
+				if httpError.StatusCode == 403 {
+					log.Infof("[Luftdaten] please register your station "+
+						"at https://devices.sensor.community/sensors/register "+
+						"(Sensor ID: %s, Sensor Board: raspi, Sensor Types: SDS011/BME280)",
+						numSensorId)
+					return
+				}
 			}
 		}
+	} else {
+		log.Debugf("[Luftdaten] %s: no PM sensor data to post", sensorId)
 	}
 
-	envSensorData := &SensorData{
-		SoftwareVersion: data.Version,
-		SensorDataValues: []SensorDataValue{
-			{ValueType: "temperature", Value: Float32RefRound(data.LastMeasurement.Temperature, 1)},
-			{ValueType: "humidity", Value: Float32RefRound(data.LastMeasurement.Humidity, 1)},
-			{ValueType: "pressure", Value: 100 * Float32RefRound(data.LastMeasurement.Pressure, 2)},
-		},
+	if envSensorData != nil {
+		if err := lf.postSensorData(sensorId, luftdatenEnvSensorPin, envSensorData); err != nil {
+			lf.status.failure(err)
+			failed = true
+		}
+	} else {
+		log.Debugf("[Luftdaten] %s: no environment sensor data to post", sensorId)
 	}
-	_ = lf.postSensorData(sensorId, 11, envSensorData)
 
+	if !failed {
+		lf.status.success()
+	}
+}
+
+// luftdatenSensorData creates PM and environment sensor data from present measurement values,
+// sensor data is nil if there are no values for the sensor
+func luftdatenSensorData(data *StationData) (pm *SensorData, env *SensorData) {
+	m := data.LastMeasurement
+
+	var pmValues []SensorDataValue
+	pmValues = appendSensorDataValue(pmValues, "P1", m.Pm10, 1, 1)
+	pmValues = appendSensorDataValue(pmValues, "P2", m.Pm25, 1, 1)
+	if len(pmValues) > 0 {
+		pm = &SensorData{
+			SoftwareVersion:  data.Version,
+			SensorDataValues: pmValues,
+		}
+	}
+
+	var envValues []SensorDataValue
+	envValues = appendSensorDataValue(envValues, "temperature", m.Temperature, 1, 1)
+	envValues = appendSensorDataValue(envValues, "humidity", m.Humidity, 1, 1)
+	envValues = appendSensorDataValue(envValues, "pressure", m.Pressure, 2, 100)
+	if data.Derived != nil {
+		envValues = appendSensorDataValue(envValues, "pressure_at_sealevel", data.Derived.SeaLevelPressure, 2, 100)
+	}
+	if len(envValues) > 0 {
+		env = &SensorData{
+			SoftwareVersion:  data.Version,
+			SensorDataValues: envValues,
+		}
+	}
+
+	return
 }
 
 func (lf *LuftdatenFeeder) postSensorData(sensorId string, sensorPin int, sensorData *SensorData) error {
@@ -192,19 +613,27 @@ type AirCmsFeeder struct {
 	sensorDataPostInterval time.Duration
 
 	lastSensorDataPostTime time.Time
+
+	status feederStatus
 }
 
 func NewAirCmsFeederFeeder() *AirCmsFeeder {
 	return &AirCmsFeeder{
 		apiServerUrl:           "http://doiot.ru/php/sensors.php",
 		sensorDataPostInterval: 3 * time.Minute,
+		status:                 newFeederStatus(FeederAirCms),
 	}
 }
 
+func (acf *AirCmsFeeder) Status() FeederStatus {
+	return acf.status.get()
+}
+
 func (acf *AirCmsFeeder) Feed(data *StationData) {
 	l, err := strconv.ParseInt(data.TokenId[12:20], 16, 64)
 	if err != nil {
 		log.Errorf("[AirCMS] can't get login from token %s: %v", data.TokenId, err)
+		acf.status.failure(err)
 		return
 	}
 	login := strconv.FormatInt(l, 10)
@@ -221,20 +650,16 @@ func (acf *AirCmsFeeder) Feed(data *StationData) {
 		data.TokenId[6:8], data.TokenId[8:10], data.TokenId[10:12])
 	token = strings.ToUpper(token)
 
-	sensorData := &SensorData{
-		SoftwareVersion: data.Version,
-		SensorDataValues: []SensorDataValue{
-			{ValueType: "SDS_P1", Value: Float32RefRound(data.LastMeasurement.Pm10, 1)},
-			{ValueType: "SDS_P2", Value: Float32RefRound(data.LastMeasurement.Pm25, 1)},
-			{ValueType: "BME280_temperature", Value: Float32RefRound(data.LastMeasurement.Temperature, 1)},
-			{ValueType: "BME280_humidity", Value: Float32RefRound(data.LastMeasurement.Humidity, 1)},
-			{ValueType: "BME280_pressure", Value: 100 * Float32RefRound(data.LastMeasurement.Pressure, 2)},
-		},
+	sensorData := NewAirrohrSensorData(data)
+	if len(sensorData.SensorDataValues) == 0 {
+		log.Debugf("[AirCMS] %s: no sensor data to post", login)
+		return
 	}
 
 	jd, err := json.Marshal(sensorData)
 	if err != nil {
 		log.Errorf("[AirCMS] %s: can't marshal sensor data: %v", login, err)
+		acf.status.failure(err)
 		return
 	}
 
@@ -255,6 +680,7 @@ func (acf *AirCmsFeeder) Feed(data *StationData) {
 	if r, err = HttpPostData(postUrl, nil, []byte(d)); err != nil {
 		log.Errorf("[AirCMS] %s: sensor data posting failed: %s", login,
 			TruncateString(err.Error(), maxFeederErrorLogLength))
+		acf.status.failure(err)
 		if httpError, ok := err.(*HttpError); ok {
 			if httpError.StatusCode == 403 {
 				log.Infof("[AirCMS] please register your station "+
@@ -265,5 +691,7 @@ func (acf *AirCmsFeeder) Feed(data *StationData) {
 		return
 	}
 
+	acf.status.success()
+
 	log.Debugf("[AirCMS] %s: successfully posted sensor data, response: %s", login, string(r))
 }
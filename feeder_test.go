@@ -0,0 +1,356 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openairtech/api"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLuftdatenSensorData(t *testing.T) {
+	temp := float32(25.04)
+	pressure := float32(1015.123)
+	pm25 := float32(1.84)
+	seaLevelPressure := float32(1020.34)
+
+	tests := []struct {
+		name    string
+		m       api.Measurement
+		derived *DerivedValues
+		pm      []SensorDataValue
+		env     []SensorDataValue
+	}{
+		{name: "no values", m: api.Measurement{}},
+		{name: "PM values only", m: api.Measurement{Pm25: &pm25},
+			pm: []SensorDataValue{{ValueType: "P2", Value: 1.8}}},
+		{name: "environment values only", m: api.Measurement{Temperature: &temp, Pressure: &pressure},
+			env: []SensorDataValue{{ValueType: "temperature", Value: 25}, {ValueType: "pressure", Value: 101512}}},
+		{name: "sea-level pressure", m: api.Measurement{Pressure: &pressure},
+			derived: &DerivedValues{SeaLevelPressure: &seaLevelPressure},
+			env: []SensorDataValue{{ValueType: "pressure", Value: 101512},
+				{ValueType: "pressure_at_sealevel", Value: 102034}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pm, env := luftdatenSensorData(&StationData{LastMeasurement: &tt.m, Derived: tt.derived})
+			if tt.pm == nil {
+				require.Nil(t, pm)
+			} else {
+				require.Equal(t, tt.pm, pm.SensorDataValues)
+			}
+			if tt.env == nil {
+				require.Nil(t, env)
+			} else {
+				require.Equal(t, tt.env, env.SensorDataValues)
+			}
+		})
+	}
+}
+
+func TestNewAirrohrSensorData_PartialMeasurement(t *testing.T) {
+	humidity := float32(33.46)
+	pm10 := float32(14.5)
+
+	sd := NewAirrohrSensorData(&StationData{
+		Version:         "test",
+		LastMeasurement: &api.Measurement{Humidity: &humidity, Pm10: &pm10},
+	})
+
+	require.Equal(t, []SensorDataValue{
+		{ValueType: "SDS_P1", Value: 14.5},
+		{ValueType: "BME280_humidity", Value: 33.5},
+	}, sd.SensorDataValues)
+}
+
+// newOpenAirTestServer creates OpenAir API test server passing posted data to given handler,
+// posting fails if handler returns false
+func newOpenAirTestServer(t *testing.T, handler func(fd *api.FeederData) bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var fd api.FeederData
+		if err := json.NewDecoder(r.Body).Decode(&fd); err != nil {
+			t.Errorf("can't decode posted data: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !handler(&fd) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		writeJson(w, api.Result{Status: api.StatusOk})
+	}))
+}
+
+// measurementTimes returns Unix times of given measurements
+func measurementTimes(measurements []api.Measurement) []int64 {
+	var ts []int64
+	for _, m := range measurements {
+		ts = append(ts, time.Time(*m.Timestamp).Unix())
+	}
+	return ts
+}
+
+func TestOpenAirFeeder_Backfill(t *testing.T) {
+	var mu sync.Mutex
+	var posts [][]int64
+	failAfter := 1
+	srv := newOpenAirTestServer(t, func(fd *api.FeederData) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(posts) >= failAfter {
+			return false
+		}
+		posts = append(posts, measurementTimes(fd.Measurements))
+		return true
+	})
+	defer srv.Close()
+
+	history := NewHistory(t.TempDir(), 24*time.Hour)
+	base := time.Now().Add(-time.Hour).Truncate(time.Minute)
+	ts := func(i int) int64 {
+		return base.Add(time.Duration(i) * time.Minute).Unix()
+	}
+	feed := func(oaf *OpenAirFeeder, i int) {
+		ut := api.UnixTime(time.Unix(ts(i), 0))
+		data := &StationData{TokenId: "0123456789abcdef", LastMeasurement: &api.Measurement{Timestamp: &ut}}
+		history.Publish(data)
+		oaf.Feed(data)
+	}
+	state := func(oaf *OpenAirFeeder) openAirFeederState {
+		require.Eventually(t, func() bool {
+			oaf.Lock()
+			defer oaf.Unlock()
+			return !oaf.backfilling
+		}, 5*time.Second, time.Millisecond)
+		oaf.Lock()
+		defer oaf.Unlock()
+		return oaf.state
+	}
+
+	// Server is unavailable for measurements 1-5, backfill fails after the first batch
+	oaf := NewOpenAirFeeder(srv.URL, time.Nanosecond, history, 2, time.Millisecond)
+	for i := 0; i <= 5; i++ {
+		feed(oaf, i)
+	}
+	mu.Lock()
+	failAfter = 3
+	mu.Unlock()
+	feed(oaf, 6)
+	require.Equal(t, openAirFeederState{HighWaterMark: ts(2), Acknowledged: ts(6), BackfillUntil: ts(6)},
+		state(oaf))
+
+	// Backfill is resumed from persisted state after restart and stopped with feeder
+	mu.Lock()
+	failAfter = 100
+	mu.Unlock()
+	oaf = NewOpenAirFeeder(srv.URL, time.Nanosecond, history, 2, time.Hour)
+	feed(oaf, 7)
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(posts) == 5
+	}, 5*time.Second, time.Millisecond)
+	oaf.Stop()
+	require.Equal(t, openAirFeederState{HighWaterMark: ts(4), Acknowledged: ts(7), BackfillUntil: ts(6)},
+		state(oaf))
+
+	oaf = NewOpenAirFeeder(srv.URL, time.Nanosecond, history, 2, time.Millisecond)
+	feed(oaf, 8)
+	require.Equal(t, openAirFeederState{HighWaterMark: ts(8), Acknowledged: ts(8)}, state(oaf))
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, [][]int64{{ts(0)}, {ts(6)}, {ts(1), ts(2)}, {ts(7)}, {ts(3), ts(4)}, {ts(8)}, {ts(5)}},
+		posts)
+}
+
+func TestOpenAirFeeder_BackfillQualityDrop(t *testing.T) {
+	var mu sync.Mutex
+	var posts [][]int64
+	available := true
+	srv := newOpenAirTestServer(t, func(fd *api.FeederData) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		if available {
+			posts = append(posts, measurementTimes(fd.Measurements))
+		}
+		return available
+	})
+	defer srv.Close()
+
+	history := NewHistory(t.TempDir(), 24*time.Hour)
+	base := time.Now().Add(-time.Hour).Truncate(time.Minute)
+	ts := func(i int) int64 {
+		return base.Add(time.Duration(i) * time.Minute).Unix()
+	}
+
+	oaf := NewOpenAirFeeder(srv.URL, time.Nanosecond, history, 10, time.Millisecond)
+	defer oaf.Stop()
+	qf := NewQualityFeeder(oaf, FeederOpenAir, []string{QualityStuck})
+	feed := func(i int, quality ...QualityFlag) {
+		ut := api.UnixTime(time.Unix(ts(i), 0))
+		data := &StationData{TokenId: "0123456789abcdef", LastMeasurement: &api.Measurement{Timestamp: &ut},
+			Quality: quality}
+		history.Publish(data)
+		qf.Feed(data)
+	}
+
+	feed(0)
+	mu.Lock()
+	available = false
+	mu.Unlock()
+	feed(1, QualityFlag{Flag: QualityStuck, Field: FieldPm25})
+	feed(2, QualityFlag{Flag: QualityJump, Field: FieldPm25})
+	feed(3, QualityFlag{Flag: QualityStuck, Field: FieldPm10})
+	mu.Lock()
+	available = true
+	mu.Unlock()
+	feed(4)
+
+	// Measurements dropped by quality flags are not backfilled
+	require.Eventually(t, func() bool {
+		oaf.Lock()
+		defer oaf.Unlock()
+		return !oaf.backfilling && oaf.state.BackfillUntil == 0
+	}, 5*time.Second, time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, [][]int64{{ts(0)}, {ts(4)}, {ts(2)}}, posts)
+}
+
+func TestOpenAirFeeder_BackfillAverages(t *testing.T) {
+	var mu sync.Mutex
+	posts := make(map[int64]float32)
+	available := true
+	srv := newOpenAirTestServer(t, func(fd *api.FeederData) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		if available {
+			for _, m := range fd.Measurements {
+				posts[time.Time(*m.Timestamp).Unix()] = *m.Pm25
+			}
+		}
+		return available
+	})
+	defer srv.Close()
+
+	history := NewHistory(t.TempDir(), 24*time.Hour)
+	base := time.Now().Add(-time.Hour).Truncate(time.Minute)
+	ts := func(i int) int64 {
+		return base.Add(time.Duration(i) * time.Minute).Unix()
+	}
+
+	oaf := NewOpenAirFeeder(srv.URL, time.Nanosecond, history, 3, time.Millisecond)
+	defer oaf.Stop()
+	oaf.SetFeedAverage(FeedAverage10m)
+	for i := 0; i <= 20; i++ {
+		mu.Lock()
+		available = i < 12 || i == 20
+		mu.Unlock()
+		ut, pm25 := api.UnixTime(time.Unix(ts(i), 0)), float32(i)
+		data := &StationData{TokenId: "0123456789abcdef",
+			LastMeasurement: &api.Measurement{Timestamp: &ut, Pm25: &pm25}}
+		history.Publish(data)
+		oaf.Feed(data)
+	}
+
+	require.Eventually(t, func() bool {
+		oaf.Lock()
+		defer oaf.Unlock()
+		return !oaf.backfilling && oaf.state.BackfillUntil == 0
+	}, 5*time.Second, time.Millisecond)
+
+	// Backfilled PM values are 10 minute averages seeded by measurements before the gap
+	mu.Lock()
+	defer mu.Unlock()
+	for i := 12; i < 20; i++ {
+		require.Equal(t, float32(i)-4.5, posts[ts(i)], "measurement %d", i)
+	}
+}
+
+func TestOpenAirFeeder_BackfillSeveralGaps(t *testing.T) {
+	history := NewHistory(t.TempDir(), 24*time.Hour)
+	base := time.Now().Add(-time.Hour).Truncate(time.Minute)
+	ts := func(i int) int64 {
+		return base.Add(time.Duration(i) * time.Minute).Unix()
+	}
+
+	var mu sync.Mutex
+	var posts [][]int64
+	available, failBackfill := true, false
+	srv := newOpenAirTestServer(t, func(fd *api.FeederData) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		times := measurementTimes(fd.Measurements)
+		if !available || (failBackfill && times[0] == ts(1)) {
+			return false
+		}
+		posts = append(posts, times)
+		return true
+	})
+	defer srv.Close()
+
+	oaf := NewOpenAirFeeder(srv.URL, time.Nanosecond, history, 10, time.Millisecond)
+	defer oaf.Stop()
+	feed := func(i int, serverAvailable bool) {
+		mu.Lock()
+		available = serverAvailable
+		mu.Unlock()
+		ut := api.UnixTime(time.Unix(ts(i), 0))
+		data := &StationData{TokenId: "0123456789abcdef", LastMeasurement: &api.Measurement{Timestamp: &ut}}
+		history.Publish(data)
+		oaf.Feed(data)
+		require.Eventually(t, func() bool {
+			oaf.Lock()
+			defer oaf.Unlock()
+			return !oaf.backfilling
+		}, 5*time.Second, time.Millisecond)
+	}
+
+	// The first gap backfill fails, then the second outage occurs
+	mu.Lock()
+	failBackfill = true
+	mu.Unlock()
+	feed(0, true)
+	feed(1, false)
+	feed(2, true)
+	feed(3, false)
+	feed(4, true)
+	oaf.Lock()
+	require.Equal(t, openAirFeederState{HighWaterMark: ts(0), Acknowledged: ts(4), BackfillUntil: ts(2),
+		Gaps: []openAirFeederGap{{After: ts(2), Until: ts(4)}}}, oaf.state)
+	oaf.Unlock()
+
+	// Both gaps are backfilled
+	mu.Lock()
+	failBackfill = false
+	mu.Unlock()
+	feed(5, true)
+	oaf.Lock()
+	require.Equal(t, openAirFeederState{HighWaterMark: ts(5), Acknowledged: ts(5)}, oaf.state)
+	oaf.Unlock()
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, [][]int64{{ts(0)}, {ts(2)}, {ts(4)}, {ts(5)}, {ts(1)}, {ts(3)}}, posts)
+}
@@ -0,0 +1,222 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/openairtech/api"
+)
+
+// Rejected value reasons
+const (
+	filterReasonRange = "range"
+	filterReasonSpike = "spike"
+)
+
+const (
+	// Median absolute deviation to standard deviation scale factor for normally distributed values
+	hampelMadScale = 1.4826
+	// Min number of previous values to detect spikes
+	hampelMinWindow = 3
+	// Min absolute and relative (to median) deviation treated as spike,
+	// prevents rejecting small changes of steady values having zero median absolute deviation
+	hampelMinDeviation         = 2
+	hampelMinRelativeDeviation = 0.25
+)
+
+// ValueRange is the valid measurement field value range
+type ValueRange struct {
+	Min float32
+	Max float32
+}
+
+func (vr ValueRange) Contains(v float32) bool {
+	return v >= vr.Min && v <= vr.Max
+}
+
+func (vr ValueRange) String() string {
+	return fmt.Sprintf("%g:%g", vr.Min, vr.Max)
+}
+
+// ParseValueRanges parses comma-separated list of field=min:max value ranges
+func ParseValueRanges(s string) (map[string]ValueRange, error) {
+	kv, err := ParseFieldMapping(s)
+	if err != nil {
+		return nil, err
+	}
+	ranges := make(map[string]ValueRange)
+	for f, r := range kv {
+		p := strings.SplitN(r, ":", 2)
+		if len(p) != 2 {
+			return nil, fmt.Errorf("invalid %s value range: %q", f, r)
+		}
+		var vr ValueRange
+		for i, v := range []*float32{&vr.Min, &vr.Max} {
+			fv, err := strconv.ParseFloat(strings.TrimSpace(p[i]), 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s value range: %q", f, r)
+			}
+			*v = float32(fv)
+		}
+		if vr.Min > vr.Max {
+			return nil, fmt.Errorf("invalid %s value range: %q", f, r)
+		}
+		ranges[f] = vr
+	}
+	return ranges, nil
+}
+
+// MeasurementFilter rejects measurement values out of valid ranges and spikes detected by Hampel filter
+// over rolling window of previous values, and optionally averages several filtered measurements into one
+type MeasurementFilter struct {
+	ranges         map[string]ValueRange
+	spikeFields    []string
+	spikeWindow    int
+	spikeThreshold float64
+	samples        int
+
+	windows map[string][]float64
+	pending []*api.Measurement
+}
+
+// NewMeasurementFilter creates filter with given field value ranges, spike detection fields,
+// window size (0 to disable spike detection) and threshold (in standard deviations),
+// and number of measurements to average (1 to disable averaging)
+func NewMeasurementFilter(ranges map[string]ValueRange, spikeFields []string, spikeWindow int,
+	spikeThreshold float64, samples int) *MeasurementFilter {
+	if samples < 1 {
+		samples = 1
+	}
+	return &MeasurementFilter{
+		ranges:         ranges,
+		spikeFields:    spikeFields,
+		spikeWindow:    spikeWindow,
+		spikeThreshold: spikeThreshold,
+		samples:        samples,
+		windows:        make(map[string][]float64),
+	}
+}
+
+// Samples returns number of measurements averaged into one
+func (mf *MeasurementFilter) Samples() int {
+	return mf.samples
+}
+
+// Filter rejects invalid measurement values by setting them to nil and returns number of rejected values
+func (mf *MeasurementFilter) Filter(m *api.Measurement) int {
+	rejected := 0
+	for _, field := range MeasurementFieldList() {
+		ref := MeasurementFieldRef(m, field)
+		if *ref == nil {
+			continue
+		}
+		v := **ref
+		if vr, ok := mf.ranges[field]; ok && !vr.Contains(v) {
+			log.Warnf("rejected %s value %g: out of range %s", field, v, vr)
+			metricRejectedValues.Inc("field", field, "reason", filterReasonRange)
+			*ref = nil
+			rejected++
+			continue
+		}
+		if mf.spikeWindow <= 0 || !StringInSlice(field, mf.spikeFields) {
+			continue
+		}
+		if median, deviation, ok := mf.checkSpike(field, float64(v)); !ok {
+			log.Warnf("rejected %s value %g: spike (median %.1f, max deviation %.1f)", field, v,
+				median, deviation)
+			metricRejectedValues.Inc("field", field, "reason", filterReasonSpike)
+			*ref = nil
+			rejected++
+		}
+	}
+	return rejected
+}
+
+// checkSpike checks value is within allowed deviation from median of previous values
+// and adds value to the field window
+func (mf *MeasurementFilter) checkSpike(field string, v float64) (median, deviation float64, ok bool) {
+	window := mf.windows[field]
+	ok = true
+	if len(window) >= hampelMinWindow {
+		median = medianOf(window)
+		deviations := make([]float64, len(window))
+		for i, w := range window {
+			deviations[i] = math.Abs(w - median)
+		}
+		deviation = math.Max(mf.spikeThreshold*hampelMadScale*medianOf(deviations),
+			math.Max(hampelMinDeviation, hampelMinRelativeDeviation*math.Abs(median)))
+		ok = math.Abs(v-median) <= deviation
+	}
+	// Rejected values are kept in window too, so lasting level changes are accepted after a while
+	window = append(window, v)
+	if len(window) > mf.spikeWindow {
+		window = window[len(window)-mf.spikeWindow:]
+	}
+	mf.windows[field] = window
+	return
+}
+
+// Add filters measurement and returns average of filtered measurements
+// or false if not enough measurements are collected yet
+func (mf *MeasurementFilter) Add(m *api.Measurement) (*api.Measurement, bool) {
+	mf.Filter(m)
+	if mf.samples == 1 {
+		return m, true
+	}
+	mf.pending = append(mf.pending, m)
+	if len(mf.pending) < mf.samples {
+		return nil, false
+	}
+	am := averageMeasurements(mf.pending)
+	mf.pending = nil
+	return am, true
+}
+
+// averageMeasurements returns measurement with average field values of given measurements
+// and timestamp of the last one
+func averageMeasurements(ms []*api.Measurement) *api.Measurement {
+	am := &api.Measurement{Timestamp: ms[len(ms)-1].Timestamp}
+	for _, field := range MeasurementFieldList() {
+		var sum float32
+		n := 0
+		for _, m := range ms {
+			if v := *MeasurementFieldRef(m, field); v != nil {
+				sum += *v
+				n++
+			}
+		}
+		if n > 0 {
+			_ = SetMeasurementField(am, field, Float32Round(sum/float32(n), 1))
+		}
+	}
+	return am
+}
+
+func medianOf(vs []float64) float64 {
+	s := append([]float64(nil), vs...)
+	sort.Float64s(s)
+	n := len(s)
+	if n%2 == 1 {
+		return s[n/2]
+	}
+	return (s[n/2-1] + s[n/2]) / 2
+}
@@ -0,0 +1,95 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/openairtech/api"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseValueRanges(t *testing.T) {
+	ranges, err := ParseValueRanges("temperature=-40:85, pm25=0:999.9")
+	require.NoError(t, err)
+	require.Equal(t, map[string]ValueRange{
+		FieldTemperature: {Min: -40, Max: 85},
+		FieldPm25:        {Min: 0, Max: 999.9},
+	}, ranges)
+
+	for _, s := range []string{"pm25=0", "pm25=a:1", "pm25=10:1", "co2=0:5000"} {
+		_, err = ParseValueRanges(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestMeasurementFilter_Filter(t *testing.T) {
+	mf := NewMeasurementFilter(map[string]ValueRange{FieldHumidity: {Min: 0, Max: 100}},
+		[]string{FieldPm25}, 5, 3, 1)
+
+	f := func(v float32) *float32 { return &v }
+
+	m := &api.Measurement{Humidity: f(101), Pm25: f(10)}
+	require.Equal(t, 1, mf.Filter(m))
+	require.Nil(t, m.Humidity)
+	require.Equal(t, float32(10), *m.Pm25)
+
+	for _, v := range []float32{11, 10, 12, 11} {
+		m = &api.Measurement{Pm25: f(v)}
+		require.Zero(t, mf.Filter(m))
+	}
+
+	// Spike is rejected
+	m = &api.Measurement{Pm25: f(250)}
+	require.Equal(t, 1, mf.Filter(m))
+	require.Nil(t, m.Pm25)
+
+	// Small change of steady value is accepted
+	m = &api.Measurement{Pm25: f(13)}
+	require.Zero(t, mf.Filter(m))
+
+	// Lasting level change is accepted
+	accepted := 0
+	for i := 0; i < 5; i++ {
+		m = &api.Measurement{Pm25: f(60)}
+		if mf.Filter(m) == 0 {
+			accepted++
+		}
+	}
+	require.Equal(t, 3, accepted)
+}
+
+func TestMeasurementFilter_Add(t *testing.T) {
+	mf := NewMeasurementFilter(nil, nil, 0, 0, 3)
+
+	f := func(v float32) *float32 { return &v }
+	ts := api.UnixTime{}
+
+	_, ok := mf.Add(&api.Measurement{Temperature: f(20), Pm25: f(10)})
+	require.False(t, ok)
+	_, ok = mf.Add(&api.Measurement{Temperature: f(21), Pm25: f(12)})
+	require.False(t, ok)
+	m, ok := mf.Add(&api.Measurement{Timestamp: &ts, Temperature: f(22.5)})
+	require.True(t, ok)
+	require.Equal(t, &ts, m.Timestamp)
+	require.Equal(t, float32(21.2), *m.Temperature)
+	require.Equal(t, float32(11), *m.Pm25)
+	require.Nil(t, m.Humidity)
+
+	// Averaging starts over
+	_, ok = mf.Add(&api.Measurement{Pm25: f(10)})
+	require.False(t, ok)
+}
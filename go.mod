@@ -7,6 +7,7 @@ require (
 	github.com/d2r2/go-bsbmp v0.0.0-20190515110334-3b4b3aea8375
 	github.com/d2r2/go-i2c v0.0.0-20181113114621-14f8dd4e89ce
 	github.com/d2r2/go-logger v0.0.0-20210606094344-60e9d1233e22
+	github.com/eclipse/paho.mqtt.golang v1.3.5
 	github.com/grandcat/zeroconf v1.0.0
 	github.com/openairtech/api v0.0.0
 	github.com/openairtech/sds011 v0.0.0-20191029135153-f4ccb629bd55
@@ -17,6 +18,7 @@ require (
 require (
 	github.com/cenkalti/backoff v2.2.1+incompatible // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/gorilla/websocket v1.4.2 // indirect
 	github.com/miekg/dns v1.1.43 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	golang.org/x/net v0.0.0-20211118161319-6a13c67c3ce4 // indirect
@@ -0,0 +1,228 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// GPIO chip name to use fake in-memory GPIO chip
+	FakeGpioChipName = "fake"
+	// GPIO line consumer label
+	gpioConsumer = "openair-station"
+	// Time to wait for sysfs GPIO line files to appear after export
+	sysfsGpioExportTimeout = time.Second
+)
+
+// Sysfs GPIO interface root directory
+var sysfsGpioRoot = "/sys/class/gpio"
+
+// GpioChip is the GPIO controller
+type GpioChip interface {
+	// OutputLine requests line with given offset as output keeping its current value if line
+	// is already configured as output (so actual line state can be read back after restart)
+	OutputLine(offset int) (GpioLine, error)
+	Close() error
+}
+
+// GpioLine is the GPIO output line
+type GpioLine interface {
+	Value() (int, error)
+	SetValue(value int) error
+	Close() error
+}
+
+// OpenGpioChip opens GPIO chip with given name (like gpiochip0) or device path using
+// GPIO character device and falling back to sysfs GPIO interface if character device is not available
+func OpenGpioChip(name string) (GpioChip, error) {
+	if name == FakeGpioChipName {
+		return NewFakeGpioChip(), nil
+	}
+	path := name
+	if !strings.ContainsRune(name, os.PathSeparator) {
+		path = filepath.Join("/dev", name)
+	}
+	chip, err := openCdevGpioChip(path)
+	if err == nil {
+		return chip, nil
+	}
+	log.Debugf("can't open GPIO character device %s: %v, trying sysfs GPIO interface", path, err)
+	sysfsChip, sysfsErr := openSysfsGpioChip(filepath.Base(path))
+	if sysfsErr != nil {
+		return nil, fmt.Errorf("can't open GPIO chip %s: %v (sysfs: %v)", name, err, sysfsErr)
+	}
+	return sysfsChip, nil
+}
+
+// Raspberry Pi 40-pin header physical pin to BCM GPIO line offset
+var rpiHeaderPinLines = map[int]int{
+	3: 2, 5: 3, 7: 4, 8: 14, 10: 15, 11: 17, 12: 18, 13: 27, 15: 22, 16: 23, 18: 24, 19: 10, 21: 9,
+	22: 25, 23: 11, 24: 8, 26: 7, 27: 0, 28: 1, 29: 5, 31: 6, 32: 12, 33: 13, 35: 19, 36: 16, 37: 26,
+	38: 20, 40: 21,
+}
+
+// RpiHeaderPinLine returns BCM GPIO line offset for given Raspberry Pi header physical pin number
+func RpiHeaderPinLine(pin int) (int, error) {
+	if line, ok := rpiHeaderPinLines[pin]; ok {
+		return line, nil
+	}
+	return 0, fmt.Errorf("header pin %d is not a GPIO pin", pin)
+}
+
+// sysfsGpioChip is the GPIO chip using deprecated sysfs GPIO interface
+type sysfsGpioChip struct {
+	base int
+}
+
+func openSysfsGpioChip(name string) (*sysfsGpioChip, error) {
+	// Sysfs GPIO chip directories are named by chip base line number,
+	// find one with device link pointing to given chip
+	chips, err := filepath.Glob(filepath.Join(sysfsGpioRoot, "gpiochip*"))
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range chips {
+		dev, err := os.Readlink(filepath.Join(c, "device"))
+		if err != nil || filepath.Base(dev) != name {
+			continue
+		}
+		b, err := ioutil.ReadFile(filepath.Join(c, "base"))
+		if err != nil {
+			return nil, err
+		}
+		base, err := strconv.Atoi(strings.TrimSpace(string(b)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid GPIO chip %s base: %v", name, err)
+		}
+		return &sysfsGpioChip{base: base}, nil
+	}
+	return nil, fmt.Errorf("GPIO chip %s not found in %s", name, sysfsGpioRoot)
+}
+
+func (sc *sysfsGpioChip) OutputLine(offset int) (GpioLine, error) {
+	n := sc.base + offset
+	dir := filepath.Join(sysfsGpioRoot, fmt.Sprintf("gpio%d", n))
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		if err := ioutil.WriteFile(filepath.Join(sysfsGpioRoot, "export"), []byte(strconv.Itoa(n)), 0200); err != nil {
+			return nil, fmt.Errorf("can't export GPIO %d: %v", n, err)
+		}
+		// Wait for udev to set line files permissions
+		for start := time.Now(); time.Since(start) < sysfsGpioExportTimeout; time.Sleep(50 * time.Millisecond) {
+			if f, err := os.OpenFile(filepath.Join(dir, "direction"), os.O_WRONLY, 0); err == nil {
+				CloseQuietly(f)
+				break
+			}
+		}
+	}
+	l := &sysfsGpioLine{dir: dir}
+	direction, err := ioutil.ReadFile(filepath.Join(dir, "direction"))
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(string(direction)) != "out" {
+		// Switch line to output with low initial value
+		if err := ioutil.WriteFile(filepath.Join(dir, "direction"), []byte("low"), 0); err != nil {
+			return nil, fmt.Errorf("can't set GPIO %d direction: %v", n, err)
+		}
+	}
+	return l, nil
+}
+
+func (sc *sysfsGpioChip) Close() error {
+	return nil
+}
+
+type sysfsGpioLine struct {
+	dir string
+}
+
+func (sl *sysfsGpioLine) Value() (int, error) {
+	b, err := ioutil.ReadFile(filepath.Join(sl.dir, "value"))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(b)))
+}
+
+func (sl *sysfsGpioLine) SetValue(value int) error {
+	return ioutil.WriteFile(filepath.Join(sl.dir, "value"), []byte(strconv.Itoa(value)), 0)
+}
+
+func (sl *sysfsGpioLine) Close() error {
+	return nil
+}
+
+// FakeGpioChip is the in-memory GPIO chip for testing purposes
+type FakeGpioChip struct {
+	sync.Mutex
+
+	values map[int]int
+}
+
+func NewFakeGpioChip() *FakeGpioChip {
+	return &FakeGpioChip{
+		values: make(map[int]int),
+	}
+}
+
+func (fc *FakeGpioChip) OutputLine(offset int) (GpioLine, error) {
+	return &fakeGpioLine{chip: fc, offset: offset}, nil
+}
+
+func (fc *FakeGpioChip) Close() error {
+	return nil
+}
+
+// LineValue returns value of line with given offset
+func (fc *FakeGpioChip) LineValue(offset int) int {
+	fc.Lock()
+	defer fc.Unlock()
+	return fc.values[offset]
+}
+
+// SetLineValue sets value of line with given offset
+func (fc *FakeGpioChip) SetLineValue(offset, value int) {
+	fc.Lock()
+	defer fc.Unlock()
+	fc.values[offset] = value
+}
+
+type fakeGpioLine struct {
+	chip   *FakeGpioChip
+	offset int
+}
+
+func (fl *fakeGpioLine) Value() (int, error) {
+	return fl.chip.LineValue(fl.offset), nil
+}
+
+func (fl *fakeGpioLine) SetValue(value int) error {
+	fl.chip.SetLineValue(fl.offset, value)
+	return nil
+}
+
+func (fl *fakeGpioLine) Close() error {
+	return nil
+}
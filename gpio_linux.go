@@ -0,0 +1,141 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// GPIO character device ABI v1 (linux/gpio.h)
+const (
+	gpioHandlesMax = 64
+
+	gpioLineFlagIsOut       = 1 << 1
+	gpioHandleRequestOutput = 1 << 1
+
+	gpioGetLineInfoIoctl         = 0xc048b402 // _IOWR(0xB4, 0x02, struct gpioline_info)
+	gpioGetLineHandleIoctl       = 0xc16cb403 // _IOWR(0xB4, 0x03, struct gpiohandle_request)
+	gpioHandleGetLineValuesIoctl = 0xc040b408 // _IOWR(0xB4, 0x08, struct gpiohandle_data)
+	gpioHandleSetLineValuesIoctl = 0xc040b409 // _IOWR(0xB4, 0x09, struct gpiohandle_data)
+)
+
+type gpioLineInfo struct {
+	lineOffset uint32
+	flags      uint32
+	name       [32]byte
+	consumer   [32]byte
+}
+
+type gpioHandleRequest struct {
+	lineOffsets   [gpioHandlesMax]uint32
+	flags         uint32
+	defaultValues [gpioHandlesMax]uint8
+	consumerLabel [32]byte
+	lines         uint32
+	fd            int32
+}
+
+type gpioHandleData struct {
+	values [gpioHandlesMax]uint8
+}
+
+func ioctl(fd uintptr, req uintptr, arg unsafe.Pointer) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, req, uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// cdevGpioChip is the GPIO chip using GPIO character device
+type cdevGpioChip struct {
+	f *os.File
+}
+
+func openCdevGpioChip(path string) (GpioChip, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &cdevGpioChip{f: f}, nil
+}
+
+func (cc *cdevGpioChip) OutputLine(offset int) (GpioLine, error) {
+	info := gpioLineInfo{lineOffset: uint32(offset)}
+	if err := ioctl(cc.f.Fd(), gpioGetLineInfoIoctl, unsafe.Pointer(&info)); err != nil {
+		return nil, fmt.Errorf("can't get GPIO line %d info: %v", offset, err)
+	}
+
+	// Read current line value if line is already configured as output
+	// to request it as output with the same value
+	value := 0
+	if info.flags&gpioLineFlagIsOut != 0 {
+		l, err := cc.requestLine(offset, 0, 0)
+		if err != nil {
+			return nil, err
+		}
+		value, err = l.Value()
+		_ = l.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return cc.requestLine(offset, gpioHandleRequestOutput, value)
+}
+
+func (cc *cdevGpioChip) requestLine(offset int, flags uint32, value int) (*cdevGpioLine, error) {
+	req := gpioHandleRequest{
+		flags: flags,
+		lines: 1,
+	}
+	req.lineOffsets[0] = uint32(offset)
+	req.defaultValues[0] = uint8(value)
+	copy(req.consumerLabel[:len(req.consumerLabel)-1], gpioConsumer)
+	if err := ioctl(cc.f.Fd(), gpioGetLineHandleIoctl, unsafe.Pointer(&req)); err != nil {
+		return nil, fmt.Errorf("can't request GPIO line %d: %v", offset, err)
+	}
+	return &cdevGpioLine{fd: int(req.fd)}, nil
+}
+
+func (cc *cdevGpioChip) Close() error {
+	return cc.f.Close()
+}
+
+type cdevGpioLine struct {
+	fd int
+}
+
+func (cl *cdevGpioLine) Value() (int, error) {
+	var data gpioHandleData
+	if err := ioctl(uintptr(cl.fd), gpioHandleGetLineValuesIoctl, unsafe.Pointer(&data)); err != nil {
+		return 0, err
+	}
+	return int(data.values[0]), nil
+}
+
+func (cl *cdevGpioLine) SetValue(value int) error {
+	var data gpioHandleData
+	data.values[0] = uint8(value)
+	return ioctl(uintptr(cl.fd), gpioHandleSetLineValuesIoctl, unsafe.Pointer(&data))
+}
+
+func (cl *cdevGpioLine) Close() error {
+	return syscall.Close(cl.fd)
+}
@@ -0,0 +1,82 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRpiHeaderPinLine(t *testing.T) {
+	line, err := RpiHeaderPinLine(7)
+	require.NoError(t, err)
+	require.Equal(t, 4, line)
+
+	_, err = RpiHeaderPinLine(6)
+	require.Error(t, err, "ground pin")
+}
+
+func TestFakeGpioChip(t *testing.T) {
+	chip, err := OpenGpioChip(FakeGpioChipName)
+	require.NoError(t, err)
+	fc := chip.(*FakeGpioChip)
+	fc.SetLineValue(4, 1)
+
+	line, err := chip.OutputLine(4)
+	require.NoError(t, err)
+	v, err := line.Value()
+	require.NoError(t, err)
+	require.Equal(t, 1, v, "line value is kept")
+
+	require.NoError(t, line.SetValue(0))
+	require.Equal(t, 0, fc.LineValue(4))
+}
+
+func TestSysfsGpioChip(t *testing.T) {
+	root := t.TempDir()
+	defer func(r string) { sysfsGpioRoot = r }(sysfsGpioRoot)
+	sysfsGpioRoot = root
+
+	// Chip gpiochip0 with base 512 and exported line 516 configured as input
+	chipDir := filepath.Join(root, "gpiochip512")
+	require.NoError(t, os.MkdirAll(chipDir, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(chipDir, "base"), []byte("512\n"), 0644))
+	require.NoError(t, os.Symlink("../../devices/platform/soc/gpiochip0", filepath.Join(chipDir, "device")))
+	lineDir := filepath.Join(root, "gpio516")
+	require.NoError(t, os.MkdirAll(lineDir, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(lineDir, "direction"), []byte("in\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(lineDir, "value"), []byte("1\n"), 0644))
+
+	_, err := openSysfsGpioChip("gpiochip1")
+	require.Error(t, err)
+
+	chip, err := openSysfsGpioChip("gpiochip0")
+	require.NoError(t, err)
+	line, err := chip.OutputLine(4)
+	require.NoError(t, err)
+
+	direction, err := ioutil.ReadFile(filepath.Join(lineDir, "direction"))
+	require.NoError(t, err)
+	require.Equal(t, "low", string(direction))
+
+	require.NoError(t, line.SetValue(1))
+	v, err := line.Value()
+	require.NoError(t, err)
+	require.Equal(t, 1, v)
+}
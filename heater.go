@@ -0,0 +1,247 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/openairtech/api"
+)
+
+const (
+	HeaterStrategyThreshold = "threshold"
+	HeaterStrategyDewPoint  = "dewpoint"
+)
+
+func HeaterStrategyList() []string {
+	return []string{HeaterStrategyThreshold, HeaterStrategyDewPoint}
+}
+
+const (
+	// Heater disabling humidity hysteresis (in percents)
+	heaterDisableHumidityHysteresis = 5
+	// Heater disabling dew point spread hysteresis (in °C)
+	heaterDisableSpreadHysteresis = 1
+	// Heater duty cycle calculation window
+	heaterDutyCycleWindow = time.Hour
+)
+
+// HeaterStrategy decides heater state by measurement values
+type HeaterStrategy interface {
+	Name() string
+	// Decide returns wanted heater state for given measurement and current heater state
+	// along with decision reason or false if measurement has not enough data to decide
+	Decide(m *api.Measurement, state HeaterState) (HeaterState, string, bool)
+}
+
+// ThresholdHeaterStrategy turns heater on when relative humidity reaches threshold
+type ThresholdHeaterStrategy struct {
+	TurnOnHumidity int
+}
+
+func (ts *ThresholdHeaterStrategy) Name() string {
+	return HeaterStrategyThreshold
+}
+
+func (ts *ThresholdHeaterStrategy) Decide(m *api.Measurement, state HeaterState) (HeaterState, string, bool) {
+	if m.Humidity == nil {
+		return state, "", false
+	}
+	humidity := int(*m.Humidity)
+	reason := fmt.Sprintf("humidity: %d%%", humidity)
+	if state == HeaterOff {
+		return humidity >= ts.TurnOnHumidity, reason, true
+	}
+	return humidity > ts.TurnOnHumidity-heaterDisableHumidityHysteresis, reason, true
+}
+
+// DewPointHeaterStrategy turns heater on when difference between air temperature
+// and dew point temperature falls to given spread
+type DewPointHeaterStrategy struct {
+	TurnOnSpread float32
+}
+
+func (ds *DewPointHeaterStrategy) Name() string {
+	return HeaterStrategyDewPoint
+}
+
+func (ds *DewPointHeaterStrategy) Decide(m *api.Measurement, state HeaterState) (HeaterState, string, bool) {
+	if m.Temperature == nil || m.Humidity == nil {
+		return state, "", false
+	}
+	spread := *m.Temperature - DewPoint(*m.Temperature, *m.Humidity)
+	reason := fmt.Sprintf("dew point spread: %.1f°C", spread)
+	if state == HeaterOff {
+		return spread <= ds.TurnOnSpread, reason, true
+	}
+	return spread < ds.TurnOnSpread+heaterDisableSpreadHysteresis, reason, true
+}
+
+// HeaterControlStatus is the heater controller state
+type HeaterControlStatus struct {
+	Strategy   string     `json:"strategy"`
+	Reason     string     `json:"reason,omitempty"`
+	DutyCycle  float32    `json:"duty_cycle"`
+	LastChange *time.Time `json:"last_change,omitempty"`
+}
+
+type heaterOnInterval struct {
+	start time.Time
+	end   time.Time
+}
+
+// HeaterController turns station heater on and off using given strategy, keeping heater
+// in current state for minimum on/off time, limiting heater on time in duty cycle window
+// and keeping heater off in given time of day ranges
+type HeaterController struct {
+	strategy     HeaterStrategy
+	minOnTime    time.Duration
+	minOffTime   time.Duration
+	maxDutyCycle float32
+	offTime      []TimeRange
+
+	state      HeaterState
+	lastChange time.Time
+	reason     string
+	intervals  []heaterOnInterval
+}
+
+func NewHeaterController(strategy HeaterStrategy, minOnTime, minOffTime time.Duration, maxDutyCycle int,
+	offTime []TimeRange) *HeaterController {
+	return &HeaterController{
+		strategy:     strategy,
+		minOnTime:    minOnTime,
+		minOffTime:   minOffTime,
+		maxDutyCycle: float32(maxDutyCycle) / 100,
+		offTime:      offTime,
+	}
+}
+
+// Start turns station heater off
+func (hc *HeaterController) Start(station Station) {
+	station.TurnHeater(HeaterOff)
+	hc.state = station.HeaterState()
+	metricHeaterState.Set(float64(pinState(hc.state)))
+}
+
+// Control decides heater state for given measurement and turns station heater accordingly
+func (hc *HeaterController) Control(station Station, m *api.Measurement, now time.Time) {
+	// Station could change heater state by itself (like on station reboot)
+	if s := station.HeaterState(); s != hc.state {
+		hc.setState(s, now)
+	}
+
+	state, reason := hc.decide(m, now)
+	hc.reason = reason
+	if state == hc.state {
+		return
+	}
+
+	if state == HeaterOn {
+		log.Infof("turning heater ON (%s)", reason)
+	} else {
+		log.Infof("turning heater OFF (%s)", reason)
+	}
+	station.TurnHeater(state)
+	if s := station.HeaterState(); s != hc.state {
+		hc.setState(s, now)
+	}
+}
+
+func (hc *HeaterController) decide(m *api.Measurement, now time.Time) (HeaterState, string) {
+	state, reason, ok := hc.strategy.Decide(m, hc.state)
+	if !ok {
+		return hc.state, "not enough measurement data"
+	}
+
+	if state == HeaterOn {
+		for _, tr := range hc.offTime {
+			if tr.Contains(now) {
+				state, reason = HeaterOff, fmt.Sprintf("%s, off time %s", reason, tr)
+				break
+			}
+		}
+	}
+
+	if state == HeaterOn && hc.maxDutyCycle < 1 {
+		if dc := hc.dutyCycle(now); dc >= hc.maxDutyCycle {
+			state, reason = HeaterOff, fmt.Sprintf("%s, duty cycle: %.0f%%", reason, dc*100)
+		}
+	}
+
+	if state != hc.state && !hc.lastChange.IsZero() {
+		if hc.state == HeaterOn && now.Sub(hc.lastChange) < hc.minOnTime {
+			return hc.state, fmt.Sprintf("%s, min on time", reason)
+		}
+		if hc.state == HeaterOff && now.Sub(hc.lastChange) < hc.minOffTime {
+			return hc.state, fmt.Sprintf("%s, min off time", reason)
+		}
+	}
+
+	return state, reason
+}
+
+func (hc *HeaterController) setState(state HeaterState, now time.Time) {
+	if state == HeaterOn {
+		hc.intervals = append(hc.intervals, heaterOnInterval{start: now})
+	} else if n := len(hc.intervals); n > 0 && hc.intervals[n-1].end.IsZero() {
+		hc.intervals[n-1].end = now
+	}
+	hc.state = state
+	hc.lastChange = now
+	metricHeaterState.Set(float64(pinState(state)))
+}
+
+// dutyCycle returns heater on time ratio in duty cycle window before given time
+func (hc *HeaterController) dutyCycle(now time.Time) float32 {
+	windowStart := now.Add(-heaterDutyCycleWindow)
+	var on time.Duration
+	i := 0
+	for _, hi := range hc.intervals {
+		end := hi.end
+		if end.IsZero() {
+			end = now
+		}
+		if end.Before(windowStart) {
+			continue
+		}
+		hc.intervals[i] = hi
+		i++
+		start := hi.start
+		if start.Before(windowStart) {
+			start = windowStart
+		}
+		on += end.Sub(start)
+	}
+	hc.intervals = hc.intervals[:i]
+	return float32(on) / float32(heaterDutyCycleWindow)
+}
+
+// Status returns heater controller state at given time
+func (hc *HeaterController) Status(now time.Time) *HeaterControlStatus {
+	hs := &HeaterControlStatus{
+		Strategy:  hc.strategy.Name(),
+		Reason:    hc.reason,
+		DutyCycle: Float32Round(hc.dutyCycle(now)*100, 1),
+	}
+	if !hc.lastChange.IsZero() {
+		lastChange := hc.lastChange
+		hs.LastChange = &lastChange
+	}
+	return hs
+}
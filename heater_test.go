@@ -0,0 +1,130 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openairtech/api"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testHeaterStation struct {
+	heaterState HeaterState
+	switches    int
+}
+
+func (ts *testHeaterStation) Version() string                { return "test" }
+func (ts *testHeaterStation) Start() error                   { return nil }
+func (ts *testHeaterStation) Stop()                          {}
+func (ts *testHeaterStation) HeaterState() HeaterState       { return ts.heaterState }
+func (ts *testHeaterStation) GetData() (*StationData, error) { return nil, nil }
+
+func (ts *testHeaterStation) TurnHeater(state HeaterState) {
+	if state != ts.heaterState {
+		ts.switches++
+	}
+	ts.heaterState = state
+}
+
+func testHumidityMeasurement(temperature, humidity float32) *api.Measurement {
+	return &api.Measurement{Temperature: &temperature, Humidity: &humidity}
+}
+
+func TestDewPointHeaterStrategy(t *testing.T) {
+	require.InDelta(t, 9.3, DewPoint(20, 50), 0.1)
+
+	ds := &DewPointHeaterStrategy{TurnOnSpread: 2}
+	// Spread is about 1.5°C
+	state, _, ok := ds.Decide(testHumidityMeasurement(10, 90), HeaterOff)
+	require.True(t, ok)
+	require.Equal(t, HeaterOn, state)
+	// Spread is about 2.6°C, still in hysteresis range
+	state, _, _ = ds.Decide(testHumidityMeasurement(10, 84), HeaterOn)
+	require.Equal(t, HeaterOn, state)
+	// Spread is about 5.2°C
+	state, _, _ = ds.Decide(testHumidityMeasurement(10, 70), HeaterOn)
+	require.Equal(t, HeaterOff, state)
+
+	_, _, ok = ds.Decide(&api.Measurement{}, HeaterOff)
+	require.False(t, ok)
+}
+
+func TestHeaterController(t *testing.T) {
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.Local)
+	wet, dry := testHumidityMeasurement(10, 80), testHumidityMeasurement(10, 40)
+
+	st := &testHeaterStation{heaterState: HeaterOn}
+	hc := NewHeaterController(&ThresholdHeaterStrategy{TurnOnHumidity: 60}, 10*time.Minute, 5*time.Minute, 50,
+		[]TimeRange{{Start: 22 * time.Hour, End: 6 * time.Hour}})
+	hc.Start(st)
+	require.Equal(t, HeaterOff, st.HeaterState())
+
+	hc.Control(st, wet, now)
+	require.Equal(t, HeaterOn, st.HeaterState())
+
+	// Minimum on time
+	hc.Control(st, dry, now.Add(5*time.Minute))
+	require.Equal(t, HeaterOn, st.HeaterState())
+	hc.Control(st, dry, now.Add(10*time.Minute))
+	require.Equal(t, HeaterOff, st.HeaterState())
+
+	// Minimum off time
+	hc.Control(st, wet, now.Add(12*time.Minute))
+	require.Equal(t, HeaterOff, st.HeaterState())
+	hc.Control(st, wet, now.Add(15*time.Minute))
+	require.Equal(t, HeaterOn, st.HeaterState())
+
+	// Max duty cycle: 10 minutes on before and 20 minutes on now in last hour
+	hc.Control(st, wet, now.Add(34*time.Minute))
+	require.Equal(t, HeaterOn, st.HeaterState())
+	hc.Control(st, wet, now.Add(35*time.Minute))
+	require.Equal(t, HeaterOff, st.HeaterState())
+	require.Equal(t, float32(50), hc.Status(now.Add(35*time.Minute)).DutyCycle)
+
+	// Off time
+	night := time.Date(2026, 1, 10, 23, 0, 0, 0, time.Local)
+	hc.Control(st, wet, night)
+	require.Equal(t, HeaterOff, st.HeaterState())
+	require.Contains(t, hc.Status(night).Reason, "off time 22:00-06:00")
+	hc.Control(st, wet, night.Add(7*time.Hour))
+	require.Equal(t, HeaterOn, st.HeaterState())
+
+	// Station heater reset is taken into account
+	st.heaterState = HeaterOff
+	hc.Control(st, wet, night.Add(7*time.Hour+time.Minute))
+	require.Equal(t, HeaterOff, st.HeaterState(), "min off time after reset")
+
+	require.Equal(t, 6, st.switches)
+}
+
+func TestParseTimeRanges(t *testing.T) {
+	trs, err := ParseTimeRanges("22:00-06:30, 12:00-13:00")
+	require.NoError(t, err)
+	require.Len(t, trs, 2)
+	require.Equal(t, "22:00-06:30", trs[0].String())
+
+	at := func(h, m int) time.Time { return time.Date(2026, 1, 1, h, m, 0, 0, time.UTC) }
+	require.True(t, trs[0].Contains(at(23, 0)))
+	require.True(t, trs[0].Contains(at(6, 29)))
+	require.False(t, trs[0].Contains(at(6, 30)))
+	require.True(t, trs[1].Contains(at(12, 30)))
+	require.False(t, trs[1].Contains(at(13, 0)))
+
+	_, err = ParseTimeRanges("22:00")
+	require.Error(t, err)
+}
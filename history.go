@@ -0,0 +1,242 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/openairtech/api"
+)
+
+const (
+	// History file name date layout
+	historyFileDateLayout = "2006-01-02"
+	// History file name extension
+	historyFileExt = ".jsonl"
+)
+
+type HistoryRecord struct {
+	Measurement api.Measurement `json:"m"`
+	// Measurement values before correction
+	RawMeasurement *api.Measurement `json:"r,omitempty"`
+	// Measurement quality flags
+	Quality []QualityFlag `json:"q,omitempty"`
+}
+
+// Time returns history record measurement time
+func (hr *HistoryRecord) Time() time.Time {
+	if hr.Measurement.Timestamp == nil {
+		return time.Time{}
+	}
+	return time.Time(*hr.Measurement.Timestamp)
+}
+
+// History keeps station measurements locally as daily JSON lines files
+type History struct {
+	sync.Mutex
+
+	dir          string
+	keepDuration time.Duration
+
+	lastCleanupDay string
+}
+
+func NewHistory(dir string, keepDuration time.Duration) *History {
+	return &History{
+		dir:          dir,
+		keepDuration: keepDuration,
+	}
+}
+
+// Dir returns history files directory
+func (h *History) Dir() string {
+	return h.dir
+}
+
+func (h *History) Start() error {
+	log.Printf("starting measurements history at %s", h.dir)
+	if err := os.MkdirAll(h.dir, 0755); err != nil {
+		return fmt.Errorf("can't create history directory: %v", err)
+	}
+	return nil
+}
+
+func (h *History) Stop() {
+	log.Print("measurements history stopped")
+}
+
+func (h *History) Publish(data *StationData) {
+	r := HistoryRecord{
+		Measurement:    *data.LastMeasurement,
+		RawMeasurement: data.RawMeasurement,
+		Quality:        data.Quality,
+	}
+	t := r.Time()
+	if t.IsZero() {
+		log.Warn("skip saving measurement without timestamp to history")
+		return
+	}
+
+	h.Lock()
+	defer h.Unlock()
+
+	if err := h.append(t, &r); err != nil {
+		log.Errorf("can't save measurement to history: %v", err)
+	}
+
+	if day := t.UTC().Format(historyFileDateLayout); day != h.lastCleanupDay {
+		h.lastCleanupDay = day
+		h.cleanup(t)
+	}
+}
+
+func (h *History) append(t time.Time, r *HistoryRecord) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(h.fileName(t), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer CloseQuietly(f)
+
+	_, err = f.Write(append(b, '\n'))
+	return err
+}
+
+// cleanup removes history files with all measurements older than keep duration
+func (h *History) cleanup(now time.Time) {
+	days, err := h.days()
+	if err != nil {
+		log.Errorf("can't list history files: %v", err)
+		return
+	}
+	for _, d := range days {
+		if now.Sub(d.AddDate(0, 0, 1)) < h.keepDuration {
+			break
+		}
+		fn := h.fileName(d)
+		log.Debugf("removing expired history file %s", fn)
+		if err := os.Remove(fn); err != nil {
+			log.Errorf("can't remove expired history file: %v", err)
+		}
+	}
+}
+
+// Records returns up to limit (0 for no limit) history records with timestamps
+// after `after` and before `before` in ascending timestamp order
+func (h *History) Records(after, before time.Time, limit int) ([]HistoryRecord, error) {
+	h.Lock()
+	defer h.Unlock()
+
+	days, err := h.days()
+	if err != nil {
+		return nil, err
+	}
+
+	var records []HistoryRecord
+	for _, d := range days {
+		if !d.AddDate(0, 0, 1).After(after) || !d.Before(before) {
+			continue
+		}
+		rs, err := h.readFile(h.fileName(d))
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rs {
+			if t := r.Time(); t.After(after) && t.Before(before) {
+				records = append(records, r)
+			}
+		}
+		if limit > 0 && len(records) >= limit {
+			break
+		}
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Time().Before(records[j].Time())
+	})
+
+	if limit > 0 && len(records) > limit {
+		records = records[:limit]
+	}
+
+	return records, nil
+}
+
+func (h *History) readFile(fn string) ([]HistoryRecord, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer CloseQuietly(f)
+
+	var records []HistoryRecord
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		var r HistoryRecord
+		if err := json.Unmarshal(s.Bytes(), &r); err != nil {
+			// Skip partially written record
+			log.Warnf("skip malformed history record in %s: %v", fn, err)
+			continue
+		}
+		records = append(records, r)
+	}
+
+	return records, s.Err()
+}
+
+// days returns sorted list of days history files exist for
+func (h *History) days() ([]time.Time, error) {
+	entries, err := os.ReadDir(h.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var days []time.Time
+	for _, e := range entries {
+		n := e.Name()
+		if e.IsDir() || !strings.HasSuffix(n, historyFileExt) {
+			continue
+		}
+		d, err := time.ParseInLocation(historyFileDateLayout, strings.TrimSuffix(n, historyFileExt), time.UTC)
+		if err != nil {
+			continue
+		}
+		days = append(days, d)
+	}
+
+	sort.Slice(days, func(i, j int) bool {
+		return days[i].Before(days[j])
+	})
+
+	return days, nil
+}
+
+func (h *History) fileName(t time.Time) string {
+	return filepath.Join(h.dir, t.UTC().Format(historyFileDateLayout)+historyFileExt)
+}
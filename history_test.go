@@ -0,0 +1,61 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openairtech/api"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testHistoryData(t time.Time, pm25 float32) *StationData {
+	ts := api.UnixTime(t)
+	return &StationData{
+		LastMeasurement: &api.Measurement{
+			Timestamp: &ts,
+			Pm25:      &pm25,
+		},
+	}
+}
+
+func TestHistory_Records(t *testing.T) {
+	h := NewHistory(t.TempDir(), 48*time.Hour)
+	require.NoError(t, h.Start())
+
+	start := time.Date(2026, 3, 1, 22, 0, 0, 0, time.UTC)
+	for i := 0; i < 6; i++ {
+		h.Publish(testHistoryData(start.Add(time.Duration(i)*time.Hour), float32(i)))
+	}
+
+	records, err := h.Records(start, start.Add(5*time.Hour), 0)
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+	require.Equal(t, start.Add(time.Hour).Unix(), records[0].Time().Unix())
+	require.Equal(t, start.Add(4*time.Hour).Unix(), records[3].Time().Unix())
+
+	records, err = h.Records(time.Time{}, start.Add(24*time.Hour), 3)
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	require.Equal(t, float32(2), *records[2].Measurement.Pm25)
+
+	// Publishing measurement four days later removes expired history files
+	h.Publish(testHistoryData(start.Add(96*time.Hour), 10))
+	records, err = h.Records(time.Time{}, start.Add(120*time.Hour), 0)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+}
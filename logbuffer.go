@@ -0,0 +1,73 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type LogEntry struct {
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	Message string    `json:"message"`
+}
+
+// LogBuffer is the logger hook keeping given number of recent log entries
+type LogBuffer struct {
+	sync.Mutex
+
+	entries []LogEntry
+	next    int
+	full    bool
+}
+
+func NewLogBuffer(size int) *LogBuffer {
+	return &LogBuffer{
+		entries: make([]LogEntry, size),
+	}
+}
+
+func (lb *LogBuffer) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (lb *LogBuffer) Fire(e *log.Entry) error {
+	lb.Lock()
+	defer lb.Unlock()
+	lb.entries[lb.next] = LogEntry{
+		Time:    e.Time,
+		Level:   e.Level.String(),
+		Message: e.Message,
+	}
+	lb.next = (lb.next + 1) % len(lb.entries)
+	if lb.next == 0 {
+		lb.full = true
+	}
+	return nil
+}
+
+// Entries returns buffered log entries from the oldest to the newest
+func (lb *LogBuffer) Entries() []LogEntry {
+	lb.Lock()
+	defer lb.Unlock()
+	entries := make([]LogEntry, 0, len(lb.entries))
+	if lb.full {
+		entries = append(entries, lb.entries[lb.next:]...)
+	}
+	return append(entries, lb.entries[:lb.next]...)
+}
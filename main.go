@@ -7,9 +7,11 @@ import (
 	"math/rand"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"regexp"
 	"runtime"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -17,12 +19,16 @@ import (
 )
 
 const (
-	StationModeEsp = "esp"
-	StationModeRpi = "rpi"
+	StationModeEsp     = "esp"
+	StationModeRpi     = "rpi"
+	StationModeLinux   = "linux"
+	StationModeEspHome = "esphome"
+	StationModeTasmota = "tasmota"
+	StationModeMqtt    = "mqtt"
 )
 
 func StationModeList() []string {
-	return []string{StationModeEsp, StationModeRpi}
+	return []string{StationModeEsp, StationModeRpi, StationModeLinux, StationModeEspHome, StationModeTasmota, StationModeMqtt}
 }
 
 const (
@@ -36,6 +42,11 @@ func FeederNameList() []string {
 	return []string{FeederAll, FeederOpenAir, FeederLuftdaten, FeederAirCms}
 }
 
+const (
+	// Number of recent log entries to keep for HTTP publisher dashboard
+	logBufferSize = 200
+)
+
 var (
 	Version   = "unknown"
 	Timestamp = "unknown"
@@ -53,6 +64,13 @@ func (s *stringArray) Set(v string) error {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == CommandCalibrate {
+		os.Exit(runCalibrate(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == CommandDiscover {
+		os.Exit(runDiscover(os.Args[2:]))
+	}
+
 	versionFlag := flag.Bool("v", false, "print the version number and quit")
 
 	debugFlag := flag.Bool("d", false, "enable debug logging")
@@ -60,13 +78,54 @@ func main() {
 	mode := flag.String("m", StationModeEsp, fmt.Sprintf("station mode (%s)",
 		SliceToString(StationModeList())))
 
-	espHost := flag.String("h", "OpenAir.local", "ESP station address")
-	espPort := flag.Int("p", 80, "ESP station port")
+	espHost := flag.String("h", "OpenAir.local", "ESP/ESPHome/Tasmota station address")
+	espPort := flag.Int("p", 80, "ESP/ESPHome/Tasmota station port")
 	espHeaterGpioPin := flag.Int("g", 14, "ESP station PM sensor heater control GPIO pin number")
-
-	rpiI2cBusId := flag.Int("i", 1, "RPi station I2C bus ID")
-	rpiSerialPort := flag.String("s", "/dev/ttyAMA0", "RPi station serial port name")
-	rpiHeaterGpioPin := flag.Int("G", 7, "RPi station PM sensor heater control GPIO pin number")
+	espMappingFile := flag.String("esp-mapping", "", fmt.Sprintf("ESP station task to measurement field "+
+		"mapping JSON file (sensor presets: %s, set \"default_plugins\" to map single tasks of common "+
+		"sensor plugins)", SliceToString(SensorPresetList())))
+
+	espHomeSensors := flag.String("esphome-sensors",
+		"temperature=temperature,humidity=humidity,pressure=pressure,pm25=pm_2_5,pm10=pm_10",
+		"ESPHome station measurement field to sensor object ID mapping")
+	espHomeUptimeSensor := flag.String("esphome-uptime", "uptime",
+		"ESPHome station uptime sensor object ID (empty to use station process uptime)")
+	espHomeMacSensor := flag.String("esphome-mac", "mac_address",
+		"ESPHome station MAC address text sensor object ID (used to generate station token ID)")
+	espHomeHeaterSwitch := flag.String("esphome-heater", "heater",
+		"ESPHome station PM sensor heater switch object ID")
+
+	tasmotaHeaterRelay := flag.Int("tasmota-heater", 1, "Tasmota station PM sensor heater relay number")
+
+	mqttBroker := flag.String("mqtt-broker", "tcp://localhost:1883", "MQTT station broker address")
+	mqttClientId := flag.String("mqtt-client-id", "openair-station", "MQTT station client ID")
+	mqttUsername := flag.String("mqtt-user", "", "MQTT station broker user name")
+	mqttPassword := flag.String("mqtt-password", "", "MQTT station broker password")
+	mqttSources := flag.String("mqtt-fields", "",
+		"MQTT station measurement field to value source mapping, value source is either topic "+
+			"with one value per message or topic@json.path for JSON payloads (like pm25=tele/sds/SENSOR@SDS0X1.PM2.5)")
+	mqttUptimeSource := flag.String("mqtt-uptime", "",
+		"MQTT station uptime (in seconds) value source (empty to use station process uptime)")
+	mqttMacSource := flag.String("mqtt-mac", "",
+		"MQTT station MAC address value source (used to generate station token ID)")
+	mqttHeaterTopic := flag.String("mqtt-heater-topic", "", "MQTT station PM sensor heater command topic")
+	mqttHeaterOn := flag.String("mqtt-heater-on", "ON", "MQTT station PM sensor heater turn on command payload")
+	mqttHeaterOff := flag.String("mqtt-heater-off", "OFF", "MQTT station PM sensor heater turn off command payload")
+	mqttMaxAge := flag.Duration("mqtt-max-age", 5*time.Minute,
+		"MQTT station max age of received values (0 to use values of any age)")
+
+	rpiI2cBusId := flag.Int("i", 1, "RPi/Linux station BME280 sensor I2C bus ID "+
+		"(-1 to disable BME280 sensor, default is -1 in Linux mode)")
+	rpiSerialPort := flag.String("s", "/dev/ttyAMA0", "RPi/Linux station SDS011 sensor serial port name "+
+		"(empty to disable SDS011 sensor, default is /dev/ttyUSB0 in Linux mode)")
+	rpiHeaterGpioPin := flag.Int("G", 7, "RPi station PM sensor heater control GPIO header pin number "+
+		"(-1 to disable heater, default is -1 in Linux mode)")
+	rpiGpioChip := flag.String("gpio-chip", "gpiochip0", fmt.Sprintf("RPi/Linux station heater control "+
+		"GPIO chip name or device path ('%s' to use in-memory GPIO chip)", FakeGpioChipName))
+	rpiHeaterGpioLine := flag.Int("gpio-line", -1, "RPi/Linux station PM sensor heater control GPIO "+
+		"chip line offset (overrides header pin number)")
+	rpiMacInterface := flag.String("mac-iface", "", "RPi/Linux station network interface to get MAC address "+
+		"for token ID generation from (default is first wireless interface, machine ID is used if there is no MAC address)")
 
 	apiServerUrl := flag.String("a", "https://api.openair.city/v1/feeder",
 		"OpenAir feeder endpoint address")
@@ -75,16 +134,84 @@ func main() {
 
 	keepDuration := flag.Duration("k", 6*time.Hour, "buffered data keep duration")
 
+	historyDir := flag.String("history", "", "measurements history directory (empty to disable history)")
+	historyKeepDuration := flag.Duration("history-keep", 30*24*time.Hour, "measurements history keep duration")
+
+	backfillBatchSize := flag.Int("backfill-batch", 100,
+		"max number of historical measurements to post to OpenAir server in one backfill batch")
+	backfillInterval := flag.Duration("backfill-interval", 10*time.Second,
+		"interval between OpenAir server backfill batches")
+
 	settleTime := flag.Duration("S", 5*time.Minute, "data settle time after station restart")
 
 	resolverTimeout := flag.Duration("r", 15*time.Second, "name resolver timeout")
+	resolverRefresh := flag.Duration("resolver-refresh", time.Minute, "interval of resolved .local host "+
+		"addresses refresh in background (0 to disable)")
 
 	httpTimeout := flag.Duration("T", 15*time.Second, "http client timeout")
 
 	disablePmCorrectionFlag := flag.Bool("c", false, "disable PM values correction by humidity")
+	pmCorrectionModel := flag.String("pm-correction", PmCorrectionGrowth, fmt.Sprintf("PM values correction "+
+		"model (%s)", SliceToString(PmCorrectionModelList())))
+	pmCorrectionProfile := flag.String("pm-calibration", "", "PM values correction profile JSON file "+
+		"with per-value models and coefficients (overrides correction model)")
+
+	filterRanges := flag.String("filter-ranges", "temperature=-40:85,humidity=0:100,pressure=300:1100,"+
+		"pm25=0:999.9,pm10=0:1999.9", "comma-separated list of valid measurement field value ranges "+
+		"(field=min:max), values out of range are rejected")
+	filterSpikeFields := flag.String("filter-spike-fields", "pm25,pm10", "comma-separated list of "+
+		"measurement fields to reject spikes of (empty to disable spike rejection)")
+	filterSpikeWindow := flag.Int("filter-spike-window", 0, "number of previous values to detect spikes by "+
+		"(0 to disable spike rejection)")
+	filterSpikeThreshold := flag.Float64("filter-spike-threshold", 3, "spike detection threshold "+
+		"(number of standard deviations from median of previous values)")
+	filterSamples := flag.Int("filter-samples", 1, "number of station measurements in update interval "+
+		"to average into one reported measurement")
+
+	altitude := flag.Float64("altitude", 0, "station altitude above sea level (m) to calculate "+
+		"sea-level pressure (sea-level pressure is not calculated if not set)")
+
+	feedAverage := flag.String("feed-average", FeedAverageNone, fmt.Sprintf("PM value average to feed "+
+		"instead of instantaneous values (%s)", SliceToString(FeedAverageList())))
+
+	diagStuckTime := flag.Duration("diag-stuck-time", 6*time.Hour, "time of unchanged sensor value "+
+		"to detect it as stuck (0 to disable)")
+	qualityDrop := flag.String("quality-drop", "", fmt.Sprintf("comma-separated list of feeder=flag1|flag2 "+
+		"pairs to drop measurements having given quality flags instead of feeding them (flags: %s, %s)",
+		SliceToString(QualityFlagList()), QualityFlagAll))
 
 	enableHeaterFlag := flag.Bool("H", false, "enable PM sensor heater (disables PM values correction by humidity)")
-	heaterTurnOnHumidity := flag.Int("R", 60, "relative humidity value threshold to turn PM sensor heater on")
+	heaterStrategy := flag.String("heater-strategy", HeaterStrategyThreshold, fmt.Sprintf("PM sensor heater "+
+		"control strategy (%s)", SliceToString(HeaterStrategyList())))
+	heaterTurnOnHumidity := flag.Int("R", 60, "relative humidity value threshold to turn PM sensor heater on "+
+		"(threshold strategy)")
+	heaterTurnOnSpread := flag.Float64("heater-spread", 2, "air and dew point temperature difference (°C) "+
+		"to turn PM sensor heater on (dew point strategy)")
+	heaterMinOnTime := flag.Duration("heater-min-on", 0, "PM sensor heater minimum on time")
+	heaterMinOffTime := flag.Duration("heater-min-off", 0, "PM sensor heater minimum off time")
+	heaterMaxDutyCycle := flag.Int("heater-max-duty", 100, fmt.Sprintf("PM sensor heater maximum duty cycle "+
+		"(percentage of on time in last %v)", heaterDutyCycleWindow))
+	heaterOffTime := flag.String("heater-off-time", "", "comma-separated list of time of day ranges "+
+		"to keep PM sensor heater off (like 22:00-06:00)")
+
+	watchdogDegradedFailures := flag.Int("watchdog-degraded", 2, "number of consecutive failed station "+
+		"data requests to treat station as degraded (station address is resolved again)")
+	watchdogOfflineFailures := flag.Int("watchdog-offline", 5, "number of consecutive failed station "+
+		"data requests to treat station as offline")
+	watchdogPowerCommand := flag.String("watchdog-power-command", "", "command to power cycle offline station")
+	watchdogPowerGpioLine := flag.Int("watchdog-power-gpio-line", -1, "GPIO chip line offset of relay "+
+		"to power cycle offline station (-1 to disable, GPIO chip is set by -gpio-chip option)")
+	watchdogPowerOffValue := flag.Int("watchdog-power-off-value", 1, "power cycle relay GPIO line value "+
+		"to turn station power off (0 for active low relays)")
+	watchdogPowerOffTime := flag.Duration("watchdog-power-off-time", 10*time.Second,
+		"station power off time when power cycling with GPIO relay")
+	watchdogPowerInterval := flag.Duration("watchdog-power-interval", 30*time.Minute,
+		"min interval between offline station power cycles")
+
+	alertConfigFile := flag.String("alert-config", "", "alerting configuration JSON file with notifiers "+
+		"and alert rules (empty to disable alerting)")
+	alertStateFile := flag.String("alert-state", "", "alert state file (default is alerts.json in "+
+		"measurements history directory, if history is enabled)")
 
 	stationTokenId := flag.String("I", "", "Station token ID (will be generated if not specified)")
 
@@ -108,10 +235,30 @@ func main() {
 		log.SetLevel(log.DebugLevel)
 	}
 
+	logBuffer := NewLogBuffer(logBufferSize)
+	log.AddHook(logBuffer)
+
 	if !StringInSlice(*mode, StationModeList()) {
 		log.Fatalf("invalid station mode: %s", *mode)
 	}
 
+	if *mode == StationModeLinux {
+		// Generic Linux host has no RPi specific hardware by default
+		setFlags := make(map[string]bool)
+		flag.Visit(func(f *flag.Flag) {
+			setFlags[f.Name] = true
+		})
+		if !setFlags["i"] {
+			*rpiI2cBusId = -1
+		}
+		if !setFlags["s"] {
+			*rpiSerialPort = "/dev/ttyUSB0"
+		}
+		if !setFlags["G"] {
+			*rpiHeaterGpioPin = -1
+		}
+	}
+
 	if *stationTokenId != "" {
 		valid, _ := regexp.MatchString(`^[0-9a-f]{40}$`, *stationTokenId)
 		if !valid {
@@ -125,7 +272,7 @@ func main() {
 
 	log.Printf("initializing station %s", version)
 
-	InitResolvers(*resolverTimeout)
+	InitResolvers(*resolverTimeout, *resolverRefresh)
 
 	InitHttp(*httpTimeout)
 
@@ -154,12 +301,26 @@ func main() {
 		}
 	}()
 
+	var history *History
+	if *historyDir != "" {
+		history = NewHistory(*historyDir, *historyKeepDuration)
+	}
+
+	openAirFeeder := NewOpenAirFeeder(*apiServerUrl, *keepDuration, history, *backfillBatchSize, *backfillInterval)
+	defer openAirFeeder.Stop()
+	openAirFeeder.SetFeedAverage(*feedAverage)
+
 	feeders := map[string]Feeder{
-		FeederOpenAir:   NewOpenAirFeeder(*apiServerUrl, *keepDuration),
+		FeederOpenAir:   openAirFeeder,
 		FeederLuftdaten: NewLuftdatenFeeder(),
 		FeederAirCms:    NewAirCmsFeederFeeder(),
 	}
 
+	drop, err := ParseQualityDrop(*qualityDrop)
+	if err != nil {
+		log.Fatalf("invalid quality drop list: %v", err)
+	}
+
 	var ef []Feeder
 	var efn []string
 
@@ -168,6 +329,9 @@ func main() {
 			!(StringInSlice(FeederAll, enabledFeeders) || StringInSlice(n, enabledFeeders)) {
 			continue
 		}
+		if flags := append(drop[FeederAll], drop[n]...); len(flags) > 0 {
+			f = NewQualityFeeder(f, n, flags)
+		}
 		ef = append(ef, f)
 		efn = append(efn, n)
 	}
@@ -176,23 +340,183 @@ func main() {
 
 	var ps []Publisher
 
+	if history != nil {
+		ps = append(ps, history)
+	}
+
+	if *alertConfigFile != "" {
+		config, err := LoadAlertConfig(*alertConfigFile)
+		if err != nil {
+			log.Fatalf("can't load alert config: %v", err)
+		}
+		stateFile := *alertStateFile
+		if stateFile == "" && history != nil {
+			stateFile = filepath.Join(history.Dir(), "alerts.json")
+		}
+		alerter, err := NewAlerter(config, stateFile)
+		if err != nil {
+			log.Fatalf("invalid alert config: %v", err)
+		}
+		ps = append(ps, alerter)
+	}
+
+	if *watchdogDegradedFailures < 1 || *watchdogOfflineFailures < *watchdogDegradedFailures {
+		log.Fatalf("invalid watchdog failure numbers: %d, %d", *watchdogDegradedFailures, *watchdogOfflineFailures)
+	}
+	var powerCycler PowerCycler
+	if *watchdogPowerCommand != "" {
+		powerCycler = NewCommandPowerCycler(*watchdogPowerCommand)
+	} else if *watchdogPowerGpioLine >= 0 {
+		if *watchdogPowerOffValue != 0 && *watchdogPowerOffValue != 1 {
+			log.Fatalf("invalid power cycle relay GPIO line off value: %d", *watchdogPowerOffValue)
+		}
+		gpc := NewGpioPowerCycler(*rpiGpioChip, *watchdogPowerGpioLine, *watchdogPowerOffValue,
+			*watchdogPowerOffTime)
+		defer CloseQuietly(gpc)
+		powerCycler = gpc
+	}
+	var watchdogHost string
+	if *mode != StationModeRpi && *mode != StationModeLinux && *mode != StationModeMqtt {
+		watchdogHost = *espHost
+	}
+	watchdog := NewWatchdog(*watchdogDegradedFailures, *watchdogOfflineFailures, watchdogHost, powerCycler,
+		*watchdogPowerInterval)
+
 	if *httpPublisherPort > 0 {
-		ps = append(ps, NewHttpPublisher(*httpPublisherPort))
+		ps = append(ps, NewHttpPublisher(*httpPublisherPort, history, logBuffer, watchdog))
 	}
 
 	var station Station
-	if *mode == StationModeEsp {
-		station = NewEspStation(version, *espHost, *espPort, *espHeaterGpioPin, *stationTokenId)
-	} else {
+	switch *mode {
+	case StationModeEsp:
+		espMapping := DefaultEspMapping()
+		if *espMappingFile != "" {
+			var err error
+			if espMapping, err = LoadEspMapping(*espMappingFile); err != nil {
+				log.Fatalf("can't load ESP station mapping: %v", err)
+			}
+		}
+		heaterPin := -1
+		if *enableHeaterFlag {
+			heaterPin = *espHeaterGpioPin
+		}
+		station = NewEspStation(version, *espHost, *espPort, heaterPin, *stationTokenId, espMapping)
+	case StationModeEspHome:
+		sensors, err := ParseFieldMapping(*espHomeSensors)
+		if err != nil {
+			log.Fatalf("invalid ESPHome station sensors: %v", err)
+		}
+		station = NewEspHomeStation(version, *espHost, *espPort, sensors, *espHomeUptimeSensor, *espHomeMacSensor,
+			*espHomeHeaterSwitch, *stationTokenId)
+	case StationModeTasmota:
+		station = NewTasmotaStation(version, *espHost, *espPort, *tasmotaHeaterRelay, *stationTokenId)
+	case StationModeMqtt:
+		sources, err := ParseMqttSources(*mqttSources)
+		if err != nil {
+			log.Fatalf("invalid MQTT station fields: %v", err)
+		}
+		if station, err = NewMqttStation(version, *mqttBroker, *mqttClientId, *mqttUsername, *mqttPassword,
+			sources, *mqttUptimeSource, *mqttMacSource, *mqttHeaterTopic, *mqttHeaterOn, *mqttHeaterOff,
+			*mqttMaxAge, *stationTokenId); err != nil {
+			log.Fatalf("can't initialize MQTT station: %v", err)
+		}
+	default:
+		// Heater GPIO line is requested only if heater is enabled
+		heaterLine := -1
+		if *enableHeaterFlag {
+			heaterLine = *rpiHeaterGpioLine
+			if heaterLine < 0 && *rpiHeaterGpioPin >= 0 {
+				var err error
+				if heaterLine, err = RpiHeaderPinLine(*rpiHeaterGpioPin); err != nil {
+					log.Fatalf("invalid heater control GPIO pin: %v", err)
+				}
+			}
+			if heaterLine < 0 {
+				log.Fatalf("can't enable PM sensor heater: heater control GPIO line is not set")
+			}
+		}
 		var err error
 		if station, err = NewRpiStation(version, *rpiI2cBusId, 0x76, *rpiSerialPort,
-			3, *rpiHeaterGpioPin, *stationTokenId); err != nil {
-			log.Fatalf("can't initialize RPi station: %v", err)
+			3, *rpiGpioChip, heaterLine, *rpiMacInterface, *stationTokenId); err != nil {
+			log.Fatalf("can't initialize %s station: %v", *mode, err)
 		}
 	}
 
-	RunStation(ctx, station, ef, ps, *updateInterval, *settleTime, *disablePmCorrectionFlag,
-		*enableHeaterFlag, *heaterTurnOnHumidity)
+	var heater *HeaterController
+	if *enableHeaterFlag {
+		var strategy HeaterStrategy
+		switch *heaterStrategy {
+		case HeaterStrategyThreshold:
+			strategy = &ThresholdHeaterStrategy{TurnOnHumidity: *heaterTurnOnHumidity}
+		case HeaterStrategyDewPoint:
+			strategy = &DewPointHeaterStrategy{TurnOnSpread: float32(*heaterTurnOnSpread)}
+		default:
+			log.Fatalf("invalid heater strategy: %s", *heaterStrategy)
+		}
+		if *heaterMaxDutyCycle <= 0 || *heaterMaxDutyCycle > 100 {
+			log.Fatalf("invalid heater max duty cycle: %d", *heaterMaxDutyCycle)
+		}
+		offTime, err := ParseTimeRanges(*heaterOffTime)
+		if err != nil {
+			log.Fatalf("invalid heater off time: %v", err)
+		}
+		heater = NewHeaterController(strategy, *heaterMinOnTime, *heaterMinOffTime, *heaterMaxDutyCycle, offTime)
+	}
+
+	var pmCorrection *PmCorrectionProfile
+	if !*disablePmCorrectionFlag {
+		var err error
+		if *pmCorrectionProfile != "" {
+			pmCorrection, err = LoadPmCorrectionProfile(*pmCorrectionProfile)
+		} else {
+			pmCorrection, err = NewPmCorrectionProfile(*pmCorrectionModel)
+		}
+		if err != nil {
+			log.Fatalf("can't initialize PM values correction: %v", err)
+		}
+	}
+
+	ranges, err := ParseValueRanges(*filterRanges)
+	if err != nil {
+		log.Fatalf("invalid filter value ranges: %v", err)
+	}
+	var spikeFields []string
+	for _, f := range strings.Split(*filterSpikeFields, ",") {
+		if f = strings.TrimSpace(f); f == "" {
+			continue
+		}
+		if !StringInSlice(f, MeasurementFieldList()) {
+			log.Fatalf("invalid filter spike field: %s", f)
+		}
+		spikeFields = append(spikeFields, f)
+	}
+	if *filterSamples < 1 {
+		log.Fatalf("invalid filter samples number: %d", *filterSamples)
+	}
+	filter := NewMeasurementFilter(ranges, spikeFields, *filterSpikeWindow, *filterSpikeThreshold, *filterSamples)
+
+	averages, err := NewPmAverages(*feedAverage)
+	if err != nil {
+		log.Fatalf("invalid feed average: %v", err)
+	}
+	if history != nil {
+		now := time.Now()
+		if records, err := history.Records(now.Add(-pmAveragesMaxWindow), now, 0); err != nil {
+			log.Errorf("can't read measurements history: %v", err)
+		} else {
+			averages.Seed(records)
+		}
+	}
+
+	var stationAltitude *float64
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "altitude" {
+			stationAltitude = altitude
+		}
+	})
+
+	RunStation(ctx, station, ef, ps, *updateInterval, *settleTime, NewDiagnostics(*diagStuckTime), filter,
+		pmCorrection, heater, stationAltitude, averages, watchdog)
 
 	log.Printf("exiting...")
 }
@@ -0,0 +1,298 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/openairtech/api"
+)
+
+// Measurement field names
+const (
+	FieldTemperature = "temperature"
+	FieldHumidity    = "humidity"
+	FieldPressure    = "pressure"
+	FieldPm25        = "pm25"
+	FieldPm10        = "pm10"
+)
+
+func MeasurementFieldList() []string {
+	return []string{FieldTemperature, FieldHumidity, FieldPressure, FieldPm25, FieldPm10}
+}
+
+// SetMeasurementField sets measurement field with given name to the value
+func SetMeasurementField(m *api.Measurement, field string, v float32) error {
+	switch field {
+	case FieldTemperature:
+		m.Temperature = &v
+	case FieldHumidity:
+		m.Humidity = &v
+	case FieldPressure:
+		m.Pressure = &v
+	case FieldPm25:
+		m.Pm25 = &v
+	case FieldPm10:
+		m.Pm10 = &v
+	default:
+		return fmt.Errorf("unknown measurement field: %s", field)
+	}
+	return nil
+}
+
+// MeasurementFieldRef returns reference to measurement field with given name or nil if field is unknown
+func MeasurementFieldRef(m *api.Measurement, field string) **float32 {
+	switch field {
+	case FieldTemperature:
+		return &m.Temperature
+	case FieldHumidity:
+		return &m.Humidity
+	case FieldPressure:
+		return &m.Pressure
+	case FieldPm25:
+		return &m.Pm25
+	case FieldPm10:
+		return &m.Pm10
+	}
+	return nil
+}
+
+// ParseFieldMapping parses comma-separated list of measurement field=source pairs
+// and checks all fields are known measurement fields
+func ParseFieldMapping(s string) (map[string]string, error) {
+	fm, err := ParseKeyValueList(s)
+	if err != nil {
+		return nil, err
+	}
+	for f := range fm {
+		if !StringInSlice(f, MeasurementFieldList()) {
+			return nil, fmt.Errorf("unknown measurement field: %s (known fields: %s)", f,
+				SliceToString(MeasurementFieldList()))
+		}
+	}
+	return fm, nil
+}
+
+// ValueMapping maps sensor value to the measurement field with optional unit scaling
+type ValueMapping struct {
+	Field  string   `json:"field"`
+	Scale  *float32 `json:"scale,omitempty"`
+	Offset float32  `json:"offset,omitempty"`
+}
+
+// Apply converts sensor value to the measurement field units
+func (vm *ValueMapping) Apply(v float32) float32 {
+	if vm.Scale != nil {
+		v *= *vm.Scale
+	}
+	return v + vm.Offset
+}
+
+// SensorMapping maps sensor value names (case-insensitive) to the measurement fields
+// either explicitly or using built-in sensor preset
+type SensorMapping struct {
+	Preset string                  `json:"preset,omitempty"`
+	Values map[string]ValueMapping `json:"values,omitempty"`
+}
+
+// Value returns mapping for sensor value with given name or nil if value is not mapped
+func (sm *SensorMapping) Value(name string) *ValueMapping {
+	for n, vm := range sm.Values {
+		if strings.EqualFold(n, name) {
+			vm := vm
+			return &vm
+		}
+	}
+	if preset, ok := sensorPresets[strings.ToLower(sm.Preset)]; ok {
+		return preset.Value(name)
+	}
+	return nil
+}
+
+func (sm *SensorMapping) validate() error {
+	if sm.Preset != "" {
+		if _, ok := sensorPresets[strings.ToLower(sm.Preset)]; !ok {
+			return fmt.Errorf("unknown sensor preset: %s (known presets: %s)", sm.Preset,
+				SliceToString(SensorPresetList()))
+		}
+	}
+	for n, vm := range sm.Values {
+		if !StringInSlice(vm.Field, MeasurementFieldList()) {
+			return fmt.Errorf("value %s: unknown measurement field: %s (known fields: %s)", n, vm.Field,
+				SliceToString(MeasurementFieldList()))
+		}
+	}
+	return nil
+}
+
+// Built-in sensor presets
+var sensorPresets = map[string]*SensorMapping{
+	"bme280": {Values: map[string]ValueMapping{
+		"Temperature": {Field: FieldTemperature},
+		"Humidity":    {Field: FieldHumidity},
+		"Pressure":    {Field: FieldPressure},
+	}},
+	"bmp280": {Values: map[string]ValueMapping{
+		"Temperature": {Field: FieldTemperature},
+		"Pressure":    {Field: FieldPressure},
+	}},
+	"bme680": {Values: map[string]ValueMapping{
+		"Temperature": {Field: FieldTemperature},
+		"Humidity":    {Field: FieldHumidity},
+		"Pressure":    {Field: FieldPressure},
+	}},
+	"sht3x": {Values: map[string]ValueMapping{
+		"Temperature": {Field: FieldTemperature},
+		"Humidity":    {Field: FieldHumidity},
+	}},
+	"dht": {Values: map[string]ValueMapping{
+		"Temperature": {Field: FieldTemperature},
+		"Humidity":    {Field: FieldHumidity},
+	}},
+	"sds011": {Values: map[string]ValueMapping{
+		"PM2.5": {Field: FieldPm25},
+		"PM10":  {Field: FieldPm10},
+	}},
+	"pmsx003": {Values: map[string]ValueMapping{
+		"pm2.5":  {Field: FieldPm25},
+		"pm10":   {Field: FieldPm10},
+		"pm10.0": {Field: FieldPm10},
+	}},
+}
+
+func SensorPresetList() []string {
+	return []string{"bme280", "bmp280", "bme680", "sht3x", "dht", "sds011", "pmsx003"}
+}
+
+// EspMapping maps ESP Easy tasks to the measurement fields by task name
+// or, if there is no mapping for task name, by task plugin type of the only task
+// of this type (both case-insensitive)
+type EspMapping struct {
+	Tasks   map[string]SensorMapping `json:"tasks,omitempty"`
+	Plugins map[string]SensorMapping `json:"plugins,omitempty"`
+	// Map single tasks of common ESP Easy environment and dust sensor plugins by plugin type
+	DefaultPlugins bool `json:"default_plugins,omitempty"`
+}
+
+// defaultEspPlugins are the common ESP Easy environment and dust sensor plugins mappings
+var defaultEspPlugins = map[string]SensorMapping{
+	"Environment - BMx280":                       {Preset: "bme280"},
+	"Environment - BME680":                       {Preset: "bme680"},
+	"Environment - SHT30/31/35":                  {Preset: "sht3x"},
+	"Environment - SHT3x":                        {Preset: "sht3x"},
+	"Environment - DHT11/12/22  SONOFF2301/7021": {Preset: "dht"},
+	"Environment - DHT12 (I2C)":                  {Preset: "dht"},
+	"Dust - SDS011/018/198":                      {Preset: "sds011"},
+	"Dust - PMSx003":                             {Preset: "pmsx003"},
+	"Dust - PMSx003 / PMSx003ST":                 {Preset: "pmsx003"},
+}
+
+// DefaultEspMapping returns mapping recognizing tasks named BME280 and SDS011
+func DefaultEspMapping() *EspMapping {
+	return &EspMapping{
+		Tasks: map[string]SensorMapping{
+			"BME280": {Preset: "bme280"},
+			"SDS011": {Preset: "sds011"},
+		},
+		Plugins: map[string]SensorMapping{},
+	}
+}
+
+// LoadEspMapping loads ESP Easy task mapping from given JSON file on top of default mapping
+func LoadEspMapping(fn string) (*EspMapping, error) {
+	b, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+
+	var fm EspMapping
+	if err := json.Unmarshal(b, &fm); err != nil {
+		return nil, fmt.Errorf("can't parse ESP mapping file %s: %v", fn, err)
+	}
+
+	em := DefaultEspMapping()
+	if fm.DefaultPlugins {
+		em.DefaultPlugins = true
+		for n, sm := range defaultEspPlugins {
+			em.Plugins[n] = sm
+		}
+	}
+	for n, sm := range fm.Tasks {
+		if err := sm.validate(); err != nil {
+			return nil, fmt.Errorf("task %s: %v", n, err)
+		}
+		setSensorMapping(em.Tasks, n, sm)
+	}
+	for n, sm := range fm.Plugins {
+		if err := sm.validate(); err != nil {
+			return nil, fmt.Errorf("plugin %s: %v", n, err)
+		}
+		setSensorMapping(em.Plugins, n, sm)
+	}
+
+	return em, nil
+}
+
+// Sensors returns mappings for given ESP Easy tasks (nil for disabled and not mapped tasks).
+// Task without mapping for its name is mapped by plugin type only if it is the only enabled task
+// of this plugin type, so values of several sensors of the same type (like indoor and outdoor ones)
+// are never mixed up
+func (em *EspMapping) Sensors(sensors []EspSensors) []*SensorMapping {
+	types := make(map[string]int)
+	for i := range sensors {
+		if sensors[i].TaskEnabled {
+			types[strings.ToLower(sensors[i].Type)]++
+		}
+	}
+	mappings := make([]*SensorMapping, len(sensors))
+	for i := range sensors {
+		s := &sensors[i]
+		if !s.TaskEnabled {
+			continue
+		}
+		if sm := findSensorMapping(em.Tasks, s.TaskName); sm != nil {
+			mappings[i] = sm
+		} else if types[strings.ToLower(s.Type)] == 1 {
+			mappings[i] = findSensorMapping(em.Plugins, s.Type)
+		}
+	}
+	return mappings
+}
+
+// setSensorMapping sets sensor mapping replacing existing mapping with case-insensitively equal name
+func setSensorMapping(mappings map[string]SensorMapping, name string, sm SensorMapping) {
+	for n := range mappings {
+		if strings.EqualFold(n, name) {
+			delete(mappings, n)
+		}
+	}
+	mappings[name] = sm
+}
+
+func findSensorMapping(mappings map[string]SensorMapping, name string) *SensorMapping {
+	if name == "" {
+		return nil
+	}
+	for n, sm := range mappings {
+		if strings.EqualFold(n, name) {
+			sm := sm
+			return &sm
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"math"
+
+	"github.com/openairtech/api"
+)
+
+// Magnus formula coefficients (Sonntag, 1990)
+const (
+	magnusA = 17.62
+	magnusB = 243.12
+)
+
+// DewPoint calculates dew point temperature (°C) for given air temperature (°C)
+// and relative humidity (%) using Magnus formula
+func DewPoint(temperature, humidity float32) float32 {
+	t, rh := float64(temperature), math.Max(float64(humidity), 0.1)
+	g := math.Log(rh/100) + magnusA*t/(magnusB+t)
+	return float32(magnusB * g / (magnusA - g))
+}
+
+// Standard atmosphere temperature lapse rate (K/m) and barometric formula exponent
+const (
+	lapseRate          = 0.0065
+	barometricExponent = 5.257
+)
+
+// saturationVaporPressure calculates saturation water vapor pressure (hPa) for given air temperature (°C)
+func saturationVaporPressure(t float64) float64 {
+	return 6.112 * math.Exp(magnusA*t/(magnusB+t))
+}
+
+// AbsoluteHumidity calculates absolute humidity (g/m³) for given air temperature (°C) and relative humidity (%)
+func AbsoluteHumidity(temperature, humidity float32) float32 {
+	t, rh := float64(temperature), float64(humidity)
+	return float32(216.7 * saturationVaporPressure(t) * rh / 100 / (t + 273.15))
+}
+
+// HeatIndex calculates heat index (°C) for given air temperature (°C) and relative humidity (%)
+// using US National Weather Service algorithm
+// https://www.wpc.ncep.noaa.gov/html/heatindex_equation.shtml
+func HeatIndex(temperature, humidity float32) float32 {
+	t, rh := float64(temperature)*9/5+32, float64(humidity)
+	hi := 0.5 * (t + 61 + (t-68)*1.2 + rh*0.094)
+	if (hi+t)/2 >= 80 {
+		hi = -42.379 + 2.04901523*t + 10.14333127*rh - 0.22475541*t*rh - 0.00683783*t*t -
+			0.05481717*rh*rh + 0.00122874*t*t*rh + 0.00085282*t*rh*rh - 0.00000199*t*t*rh*rh
+		if rh < 13 && t >= 80 && t <= 112 {
+			hi -= (13 - rh) / 4 * math.Sqrt((17-math.Abs(t-95))/17)
+		} else if rh > 85 && t >= 80 && t <= 87 {
+			hi += (rh - 85) / 10 * (87 - t) / 5
+		}
+	}
+	return float32((hi - 32) * 5 / 9)
+}
+
+// SeaLevelPressure reduces station pressure (hPa) to sea level for given air temperature (°C)
+// and station altitude (m) using barometric formula
+func SeaLevelPressure(pressure, temperature float32, altitude float64) float32 {
+	t := float64(temperature)
+	return float32(float64(pressure) *
+		math.Pow(1-lapseRate*altitude/(t+lapseRate*altitude+273.15), -barometricExponent))
+}
+
+// DerivedValues are the meteorological quantities derived from measurement values,
+// values which can't be derived are omitted
+type DerivedValues struct {
+	DewPoint         *float32 `json:"dew_point,omitempty"`
+	AbsoluteHumidity *float32 `json:"absolute_humidity,omitempty"`
+	HeatIndex        *float32 `json:"heat_index,omitempty"`
+	SeaLevelPressure *float32 `json:"sea_level_pressure,omitempty"`
+}
+
+// NewDerivedValues derives values from given measurement and station altitude (nil if unknown)
+func NewDerivedValues(m *api.Measurement, altitude *float64) *DerivedValues {
+	dv := &DerivedValues{}
+	round := func(v float32) *float32 {
+		v = Float32Round(v, 1)
+		return &v
+	}
+	if m.Temperature != nil && m.Humidity != nil {
+		dv.DewPoint = round(DewPoint(*m.Temperature, *m.Humidity))
+		dv.AbsoluteHumidity = round(AbsoluteHumidity(*m.Temperature, *m.Humidity))
+		dv.HeatIndex = round(HeatIndex(*m.Temperature, *m.Humidity))
+	}
+	if m.Pressure != nil && m.Temperature != nil && altitude != nil {
+		dv.SeaLevelPressure = round(SeaLevelPressure(*m.Pressure, *m.Temperature, *altitude))
+	}
+	if *dv == (DerivedValues{}) {
+		return nil
+	}
+	return dv
+}
+
+// updateMetrics sets derived value gauges, removing ones for absent values
+func (dv *DerivedValues) updateMetrics() {
+	gauges := map[*Gauge]*float32{
+		metricDewPoint:         nil,
+		metricAbsoluteHumidity: nil,
+		metricHeatIndex:        nil,
+		metricSeaLevelPressure: nil,
+	}
+	if dv != nil {
+		gauges[metricDewPoint] = dv.DewPoint
+		gauges[metricAbsoluteHumidity] = dv.AbsoluteHumidity
+		gauges[metricHeatIndex] = dv.HeatIndex
+		gauges[metricSeaLevelPressure] = dv.SeaLevelPressure
+	}
+	for g, v := range gauges {
+		if v != nil {
+			g.Set(float64(*v))
+		} else {
+			g.Delete()
+		}
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/openairtech/api"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDerivedQuantities(t *testing.T) {
+	require.InDelta(t, 8.6, AbsoluteHumidity(20, 50), 0.1)
+	require.InDelta(t, 19.4, HeatIndex(20, 50), 0.1)
+	require.InDelta(t, 41.1, HeatIndex(32.22, 70), 0.2)
+	require.InDelta(t, 1012.4, SeaLevelPressure(950, 15, 540), 0.2)
+	require.Equal(t, float32(950), SeaLevelPressure(950, 15, 0))
+}
+
+func TestNewDerivedValues(t *testing.T) {
+	temperature, humidity, pressure := float32(20), float32(50), float32(950)
+
+	require.Nil(t, NewDerivedValues(&api.Measurement{Pressure: &pressure}, nil))
+
+	dv := NewDerivedValues(&api.Measurement{Temperature: &temperature, Humidity: &humidity, Pressure: &pressure}, nil)
+	require.Equal(t, float32(9.3), *dv.DewPoint)
+	require.Equal(t, float32(8.6), *dv.AbsoluteHumidity)
+	require.Equal(t, float32(19.4), *dv.HeatIndex)
+	require.Nil(t, dv.SeaLevelPressure)
+
+	altitude := 540.0
+	dv = NewDerivedValues(&api.Measurement{Temperature: &temperature, Pressure: &pressure}, &altitude)
+	require.Nil(t, dv.DewPoint)
+	require.Equal(t, float32(1011.3), *dv.SeaLevelPressure)
+}
@@ -0,0 +1,182 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	metricTypeCounter = "counter"
+	metricTypeGauge   = "gauge"
+)
+
+// Station metrics registry
+var metrics = NewMetricsRegistry()
+
+// Station metrics
+var (
+	metricHeaterState = metrics.NewGauge("openair_heater_state",
+		"PM sensor heater state (1 is on)")
+	metricHeaterMismatches = metrics.NewCounter("openair_heater_state_mismatches_total",
+		"Number of PM sensor heater actual and requested state mismatches")
+	metricRejectedValues = metrics.NewCounter("openair_rejected_values_total",
+		"Number of measurement values rejected by filter")
+	metricQualityFlags = metrics.NewGauge("openair_quality_flag",
+		"Detected sensor fault (1 while fault is detected)")
+	metricStationState = metrics.NewGauge("openair_station_state",
+		"Station connection state (1 for current state)")
+	metricStationFailures = metrics.NewCounter("openair_station_data_failures_total",
+		"Number of failed station data requests")
+	metricStationPowerCycles = metrics.NewCounter("openair_station_power_cycles_total",
+		"Number of offline station power cycles")
+	metricDewPoint = metrics.NewGauge("openair_dew_point_celsius",
+		"Dew point temperature")
+	metricAbsoluteHumidity = metrics.NewGauge("openair_absolute_humidity_grams_per_cubic_meter",
+		"Absolute humidity")
+	metricHeatIndex = metrics.NewGauge("openair_heat_index_celsius",
+		"Heat index (apparent temperature)")
+	metricSeaLevelPressure = metrics.NewGauge("openair_sea_level_pressure_hpa",
+		"Atmospheric pressure reduced to sea level")
+)
+
+type metricFamily struct {
+	name   string
+	help   string
+	typ    string
+	values map[string]float64
+}
+
+// MetricsRegistry keeps metric values and exposes them in Prometheus text format
+type MetricsRegistry struct {
+	sync.Mutex
+
+	families []*metricFamily
+}
+
+func NewMetricsRegistry() *MetricsRegistry {
+	return &MetricsRegistry{}
+}
+
+func (mr *MetricsRegistry) newFamily(name, help, typ string) *metricFamily {
+	mr.Lock()
+	defer mr.Unlock()
+	mf := &metricFamily{name: name, help: help, typ: typ, values: make(map[string]float64)}
+	mr.families = append(mr.families, mf)
+	return mf
+}
+
+// Counter is the monotonically increasing metric
+type Counter struct {
+	registry *MetricsRegistry
+	family   *metricFamily
+}
+
+func (mr *MetricsRegistry) NewCounter(name, help string) *Counter {
+	return &Counter{registry: mr, family: mr.newFamily(name, help, metricTypeCounter)}
+}
+
+// Inc increments counter with given label name and value pairs
+func (c *Counter) Inc(labels ...string) {
+	c.Add(1, labels...)
+}
+
+// Add adds given value to counter with given label name and value pairs
+func (c *Counter) Add(v float64, labels ...string) {
+	c.registry.Lock()
+	defer c.registry.Unlock()
+	c.family.values[metricLabels(labels)] += v
+}
+
+// Gauge is the metric with arbitrary value
+type Gauge struct {
+	registry *MetricsRegistry
+	family   *metricFamily
+}
+
+func (mr *MetricsRegistry) NewGauge(name, help string) *Gauge {
+	return &Gauge{registry: mr, family: mr.newFamily(name, help, metricTypeGauge)}
+}
+
+// Set sets value of gauge with given label name and value pairs
+func (g *Gauge) Set(v float64, labels ...string) {
+	g.registry.Lock()
+	defer g.registry.Unlock()
+	g.family.values[metricLabels(labels)] = v
+}
+
+// Delete removes gauge value with given label name and value pairs
+func (g *Gauge) Delete(labels ...string) {
+	g.registry.Lock()
+	defer g.registry.Unlock()
+	delete(g.family.values, metricLabels(labels))
+}
+
+// WriteText writes metrics having values in Prometheus text exposition format
+func (mr *MetricsRegistry) WriteText(w io.Writer) error {
+	mr.Lock()
+	defer mr.Unlock()
+	for _, mf := range mr.families {
+		if len(mf.values) == 0 {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", mf.name, mf.help, mf.name, mf.typ); err != nil {
+			return err
+		}
+		labels := make([]string, 0, len(mf.values))
+		for l := range mf.values {
+			labels = append(labels, l)
+		}
+		sort.Strings(labels)
+		for _, l := range labels {
+			v := strconv.FormatFloat(mf.values[l], 'g', -1, 64)
+			if _, err := fmt.Fprintf(w, "%s%s %s\n", mf.name, l, v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (mr *MetricsRegistry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = mr.WriteText(w)
+}
+
+// metricLabels formats label name and value pairs as Prometheus labels string
+func metricLabels(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i := 0; i+1 < len(labels); i += 2 {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(labels[i])
+		sb.WriteString(`="`)
+		sb.WriteString(strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(labels[i+1]))
+		sb.WriteByte('"')
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
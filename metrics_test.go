@@ -0,0 +1,45 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsRegistry_WriteText(t *testing.T) {
+	mr := NewMetricsRegistry()
+	c := mr.NewCounter("test_requests_total", "Number of requests")
+	g := mr.NewGauge("test_value", "Test value")
+	mr.NewGauge("test_unused", "Metric without values")
+
+	c.Inc("feeder", "luftdaten")
+	c.Add(2, "feeder", "luftdaten")
+	c.Inc("feeder", `quoted "name"`)
+	g.Set(21.5)
+
+	var sb strings.Builder
+	require.NoError(t, mr.WriteText(&sb))
+	require.Equal(t, `# HELP test_requests_total Number of requests
+# TYPE test_requests_total counter
+test_requests_total{feeder="luftdaten"} 3
+test_requests_total{feeder="quoted \"name\""} 1
+# HELP test_value Test value
+# TYPE test_value gauge
+test_value 21.5
+`, sb.String())
+}
@@ -0,0 +1,391 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/openairtech/api"
+)
+
+const (
+	// MQTT broker connect/subscribe/publish operations timeout
+	mqttOperationTimeout = 10 * time.Second
+	// MQTT topic and JSON path separator in value source specification
+	mqttSourcePathSeparator = "@"
+	// Cached values keys for non-measurement sources
+	mqttUptimeKey = "_uptime"
+	mqttMacKey    = "_mac"
+)
+
+// MqttSource is the MQTT topic with optional dot-separated JSON path
+// to the value in message payload (whole payload is the value if path is empty)
+type MqttSource struct {
+	Topic string
+	Path  string
+}
+
+// ParseMqttSource parses MQTT value source specification like `topic` or `topic@json.path`
+func ParseMqttSource(s string) (MqttSource, error) {
+	p := strings.SplitN(strings.TrimSpace(s), mqttSourcePathSeparator, 2)
+	ms := MqttSource{Topic: p[0]}
+	if len(p) == 2 {
+		ms.Path = p[1]
+	}
+	if ms.Topic == "" || strings.ContainsAny(ms.Topic, "+#") {
+		return ms, fmt.Errorf("invalid MQTT topic: %q", ms.Topic)
+	}
+	return ms, nil
+}
+
+// ParseMqttSources parses comma-separated list of measurement field=source pairs
+func ParseMqttSources(s string) (map[string]MqttSource, error) {
+	fm, err := ParseFieldMapping(s)
+	if err != nil {
+		return nil, err
+	}
+	sources := make(map[string]MqttSource)
+	for f, src := range fm {
+		ms, err := ParseMqttSource(src)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %v", f, err)
+		}
+		sources[f] = ms
+	}
+	return sources, nil
+}
+
+// JsonPathValue returns value at given dot-separated path (array elements are addressed by index,
+// object keys containing dots like `PM2.5` are matched as well)
+func JsonPathValue(v interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return v, true
+	}
+	return jsonPathValue(v, strings.Split(path, "."))
+}
+
+func jsonPathValue(v interface{}, keys []string) (interface{}, bool) {
+	if len(keys) == 0 {
+		return v, true
+	}
+	switch c := v.(type) {
+	case map[string]interface{}:
+		for i := 1; i <= len(keys); i++ {
+			if e, ok := c[strings.Join(keys[:i], ".")]; ok {
+				if r, ok := jsonPathValue(e, keys[i:]); ok {
+					return r, true
+				}
+			}
+		}
+	case []interface{}:
+		i, err := strconv.Atoi(keys[0])
+		if err == nil && i >= 0 && i < len(c) {
+			return jsonPathValue(c[i], keys[1:])
+		}
+	}
+	return nil, false
+}
+
+type mqttValue struct {
+	value    interface{}
+	received time.Time
+}
+
+// MqttStation caches latest sensor values received from MQTT broker
+// and controls heater by publishing command messages
+type MqttStation struct {
+	sync.Mutex
+
+	version string
+
+	tokenId string
+
+	options *mqtt.ClientOptions
+	client  mqtt.Client
+
+	sources map[string]MqttSource
+	values  map[string]mqttValue
+	maxAge  time.Duration
+
+	macAddress string
+
+	heaterTopic string
+	heaterOn    string
+	heaterOff   string
+	heaterState HeaterState
+
+	startTime  time.Time
+	lastUptime *time.Duration
+}
+
+func NewMqttStation(version, broker, clientId, username, password string, sources map[string]MqttSource,
+	uptimeSource, macSource string, heaterTopic, heaterOn, heaterOff string, maxAge time.Duration,
+	tokenId string) (*MqttStation, error) {
+	ms := &MqttStation{
+		version:     version,
+		tokenId:     tokenId,
+		sources:     make(map[string]MqttSource),
+		values:      make(map[string]mqttValue),
+		maxAge:      maxAge,
+		heaterTopic: heaterTopic,
+		heaterOn:    heaterOn,
+		heaterOff:   heaterOff,
+	}
+
+	for f, src := range sources {
+		ms.sources[f] = src
+	}
+	for k, s := range map[string]string{mqttUptimeKey: uptimeSource, mqttMacKey: macSource} {
+		if s == "" {
+			continue
+		}
+		src, err := ParseMqttSource(s)
+		if err != nil {
+			return nil, err
+		}
+		ms.sources[k] = src
+	}
+	if len(ms.sources) == 0 {
+		return nil, errors.New("no MQTT value sources configured")
+	}
+
+	ms.options = mqtt.NewClientOptions().
+		AddBroker(broker).
+		SetClientID(clientId).
+		SetUsername(username).
+		SetPassword(password).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetOnConnectHandler(ms.subscribe).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			log.Warnf("MQTT broker connection lost: %v", err)
+		})
+
+	return ms, nil
+}
+
+func (ms *MqttStation) Version() string {
+	return ms.version
+}
+
+func (ms *MqttStation) Start() error {
+	ms.startTime = time.Now()
+
+	ms.client = mqtt.NewClient(ms.options)
+	t := ms.client.Connect()
+	if !t.WaitTimeout(mqttOperationTimeout) {
+		log.Warnf("can't connect to MQTT broker in %v, will keep retrying", mqttOperationTimeout)
+	} else if t.Error() != nil {
+		return t.Error()
+	}
+
+	log.Print("started MQTT station")
+	return nil
+}
+
+func (ms *MqttStation) Stop() {
+	if ms.client != nil {
+		ms.client.Disconnect(uint(mqttOperationTimeout.Milliseconds()))
+		ms.client = nil
+	}
+	log.Print("stopped MQTT station")
+}
+
+// subscribe subscribes to all value source topics, called on every (re)connect
+func (ms *MqttStation) subscribe(c mqtt.Client) {
+	log.Debug("connected to MQTT broker")
+	filters := make(map[string]byte)
+	for _, src := range ms.sources {
+		filters[src.Topic] = 0
+	}
+	t := c.SubscribeMultiple(filters, func(_ mqtt.Client, m mqtt.Message) {
+		ms.handleMessage(m.Topic(), m.Payload())
+	})
+	if !t.WaitTimeout(mqttOperationTimeout) {
+		log.Errorf("can't subscribe to MQTT topics: timeout")
+	} else if t.Error() != nil {
+		log.Errorf("can't subscribe to MQTT topics: %v", t.Error())
+	}
+}
+
+func (ms *MqttStation) handleMessage(topic string, payload []byte) {
+	log.Debugf("received MQTT message %s: %s", topic, payload)
+
+	var doc interface{}
+	var docErr error
+	parsed := false
+
+	now := time.Now()
+
+	ms.Lock()
+	defer ms.Unlock()
+
+	for k, src := range ms.sources {
+		if src.Topic != topic {
+			continue
+		}
+		if src.Path == "" {
+			ms.values[k] = mqttValue{strings.TrimSpace(string(payload)), now}
+			continue
+		}
+		if !parsed {
+			docErr = json.Unmarshal(payload, &doc)
+			parsed = true
+			if docErr != nil {
+				log.Errorf("can't parse MQTT message %s JSON payload: %v", topic, docErr)
+			}
+		}
+		if docErr != nil {
+			// Raw payload sources of the same topic are still updated
+			continue
+		}
+		if v, ok := JsonPathValue(doc, src.Path); ok {
+			ms.values[k] = mqttValue{v, now}
+		} else {
+			log.Debugf("no value at path %s in MQTT message %s", src.Path, topic)
+		}
+	}
+}
+
+// value returns cached value with given key or nil if there is no value or measurement value
+// is outdated (uptime and MAC address values are usually published rarely, so they never expire)
+func (ms *MqttStation) value(key string) interface{} {
+	v, ok := ms.values[key]
+	if !ok || (ms.maxAge > 0 && key != mqttUptimeKey && key != mqttMacKey && time.Since(v.received) > ms.maxAge) {
+		return nil
+	}
+	return v.value
+}
+
+func (ms *MqttStation) numericValue(key string) *float32 {
+	var f float64
+	switch v := ms.value(key).(type) {
+	case float64:
+		f = v
+	case string:
+		var err error
+		if f, err = strconv.ParseFloat(v, 32); err != nil {
+			log.Debugf("non-numeric %s value: %q", key, v)
+			return nil
+		}
+	default:
+		return nil
+	}
+	r := float32(f)
+	return &r
+}
+
+func (ms *MqttStation) HeaterState() HeaterState {
+	return ms.heaterState
+}
+
+func (ms *MqttStation) TurnHeater(state HeaterState) {
+	if ms.heaterTopic == "" {
+		log.Error("can't turn heater: MQTT heater command topic is not set")
+		return
+	}
+	if ms.client == nil {
+		log.Error("can't turn heater: MQTT station is stopped")
+		return
+	}
+
+	payload := ms.heaterOff
+	if state == HeaterOn {
+		payload = ms.heaterOn
+	}
+
+	t := ms.client.Publish(ms.heaterTopic, 1, false, payload)
+	if !t.WaitTimeout(mqttOperationTimeout) {
+		log.Errorf("can't publish heater command %s to %s: timeout", payload, ms.heaterTopic)
+		return
+	} else if t.Error() != nil {
+		log.Errorf("can't publish heater command %s to %s: %v", payload, ms.heaterTopic, t.Error())
+		return
+	}
+
+	ms.heaterState = state
+
+	if state == HeaterOn {
+		log.Debug("heater turned on")
+	} else {
+		log.Debug("heater turned off")
+	}
+}
+
+func (ms *MqttStation) GetData() (*StationData, error) {
+	ms.Lock()
+	defer ms.Unlock()
+
+	m := &api.Measurement{}
+	timestamp := api.UnixTime(time.Now())
+	m.Timestamp = &timestamp
+
+	n := 0
+	for _, f := range MeasurementFieldList() {
+		if _, ok := ms.sources[f]; !ok {
+			continue
+		}
+		if v := ms.numericValue(f); v != nil {
+			_ = SetMeasurementField(m, f, *v)
+			n++
+		} else {
+			log.Debugf("no recent %s value", f)
+		}
+	}
+	if n == 0 {
+		return nil, errors.New("no recent MQTT sensor values")
+	}
+
+	if v, ok := ms.value(mqttMacKey).(string); ok && v != "" {
+		ms.macAddress = v
+	}
+	macAddress := ms.macAddress
+
+	tokenId := ms.tokenId
+	if tokenId == "" {
+		if macAddress == "" {
+			return nil, errors.New("can't determine MQTT station MAC address")
+		}
+		tokenId = stationTokenId(macAddress)
+	}
+	log.Debugf("token ID: %s", tokenId)
+
+	uptime := time.Since(ms.startTime)
+	if v := ms.numericValue(mqttUptimeKey); v != nil {
+		uptime = time.Duration(*v) * time.Second
+		if ms.lastUptime != nil && uptime < *ms.lastUptime {
+			log.Warn("MQTT station reboot detected")
+			ms.heaterState = HeaterOff
+		}
+		ms.lastUptime = &uptime
+	}
+
+	return &StationData{
+		Version:         ms.version,
+		TokenId:         tokenId,
+		MacAddress:      macAddress,
+		Uptime:          uptime,
+		LastMeasurement: m,
+	}, nil
+}
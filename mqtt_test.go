@@ -0,0 +1,95 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMqttSources(t *testing.T) {
+	sources, err := ParseMqttSources("temperature=sensors/room/temperature,pm10=tele/sds/SENSOR@SDS0X1.PM10")
+	require.NoError(t, err)
+	require.Equal(t, map[string]MqttSource{
+		FieldTemperature: {Topic: "sensors/room/temperature"},
+		FieldPm10:        {Topic: "tele/sds/SENSOR", Path: "SDS0X1.PM10"},
+	}, sources)
+
+	_, err = ParseMqttSources("humidity=sensors/+/humidity")
+	require.Error(t, err)
+	_, err = ParseMqttSources("dust=sensors/dust")
+	require.Error(t, err)
+}
+
+func TestMqttStation_GetData(t *testing.T) {
+	sources := map[string]MqttSource{
+		FieldTemperature: {Topic: "sensors/room/temperature"},
+		FieldHumidity:    {Topic: "tele/station/SENSOR", Path: "BME280.Humidity"},
+		FieldPressure:    {Topic: "tele/station/SENSOR", Path: "BME280.values.0"},
+		FieldPm25:        {Topic: "tele/station/SENSOR", Path: "SDS0X1.PM2.5"},
+		FieldPm10:        {Topic: "tele/station/SENSOR", Path: "SDS0X1.PM10"},
+	}
+	ms, err := NewMqttStation("test", "tcp://localhost:1883", "test", "", "", sources,
+		"tele/station/STATE@UptimeSec", "tele/station/INFO@Mac", "", "ON", "OFF", time.Minute, "")
+	require.NoError(t, err)
+
+	_, err = ms.GetData()
+	require.Error(t, err, "no values received yet")
+
+	ms.handleMessage("sensors/room/temperature", []byte(" 21.5\n"))
+	ms.handleMessage("tele/station/SENSOR",
+		[]byte(`{"BME280":{"Humidity":40.5,"values":[1013.2]},"SDS0X1":{"PM2.5":3.5,"PM10":"n/a"}}`))
+	ms.handleMessage("tele/station/INFO", []byte(`{"Mac":"A4:CF:12:00:11:22"}`))
+	ms.handleMessage("tele/station/STATE", []byte(`{"UptimeSec":120}`))
+	ms.handleMessage("tele/station/OTHER", []byte(`not a JSON`))
+
+	data, err := ms.GetData()
+	require.NoError(t, err)
+	require.Equal(t, stationTokenId("A4:CF:12:00:11:22"), data.TokenId)
+	require.Equal(t, 120*time.Second, data.Uptime)
+
+	m := data.LastMeasurement
+	require.Equal(t, float32(21.5), *m.Temperature)
+	require.Equal(t, float32(40.5), *m.Humidity)
+	require.Equal(t, float32(1013.2), *m.Pressure)
+	require.Equal(t, float32(3.5), *m.Pm25)
+	require.Nil(t, m.Pm10)
+
+	// Outdated values are ignored
+	ms.values[FieldTemperature] = mqttValue{"21.5", time.Now().Add(-2 * time.Minute)}
+	data, err = ms.GetData()
+	require.NoError(t, err)
+	require.Nil(t, data.LastMeasurement.Temperature)
+
+	// Rarely published MAC address never expires
+	ms.values[mqttMacKey] = mqttValue{"A4:CF:12:00:11:22", time.Now().Add(-time.Hour)}
+	data, err = ms.GetData()
+	require.NoError(t, err)
+	require.Equal(t, stationTokenId("A4:CF:12:00:11:22"), data.TokenId)
+
+	// Raw payload source is updated even if JSON payload of the same topic can't be parsed
+	ms.sources[FieldTemperature] = MqttSource{Topic: "tele/station/SENSOR"}
+	ms.handleMessage("tele/station/SENSOR", []byte(`{"BME280":`))
+	require.Equal(t, `{"BME280":`, ms.values[FieldTemperature].value)
+
+	// Uptime decrease resets heater state
+	ms.heaterState = HeaterOn
+	ms.handleMessage("tele/station/STATE", []byte(`{"UptimeSec":5}`))
+	_, err = ms.GetData()
+	require.NoError(t, err)
+	require.Equal(t, HeaterOff, ms.HeaterState())
+}
@@ -0,0 +1,288 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Notifier types
+const (
+	NotifierWebhook  = "webhook"
+	NotifierSmtp     = "smtp"
+	NotifierTelegram = "telegram"
+	NotifierNtfy     = "ntfy"
+)
+
+func NotifierTypeList() []string {
+	return []string{NotifierWebhook, NotifierSmtp, NotifierTelegram, NotifierNtfy}
+}
+
+// Telegram Bot API server address
+var telegramApiUrl = "https://api.telegram.org"
+
+// SMTP server connection and mail sending timeout
+const smtpTimeout = 30 * time.Second
+
+// AlertEvent is the alert state change notification
+type AlertEvent struct {
+	Rule      string    `json:"rule"`
+	State     string    `json:"state"`
+	Quantity  string    `json:"quantity"`
+	Value     *float64  `json:"value,omitempty"`
+	Threshold *float64  `json:"threshold,omitempty"`
+	TokenId   string    `json:"token_id,omitempty"`
+	Time      time.Time `json:"time"`
+	Message   string    `json:"message"`
+}
+
+// Title returns event short description
+func (e *AlertEvent) Title() string {
+	return fmt.Sprintf("OpenAir station alert %s: %s", e.State, e.Rule)
+}
+
+// Notifier sends alert event notifications
+type Notifier interface {
+	Name() string
+	Notify(e *AlertEvent) error
+}
+
+// NotifierConfig is the notifier configuration, only fields used by notifier type are required
+type NotifierConfig struct {
+	// Notifier name referenced by alert rules (defaults to notifier type)
+	Name string `json:"name,omitempty"`
+	Type string `json:"type"`
+	// Webhook and ntfy topic URL
+	Url string `json:"url,omitempty"`
+	// Telegram bot token and ntfy access token
+	Token string `json:"token,omitempty"`
+	// Telegram chat ID
+	ChatId string `json:"chat_id,omitempty"`
+	// SMTP server settings
+	Host     string   `json:"host,omitempty"`
+	Port     int      `json:"port,omitempty"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from,omitempty"`
+	To       []string `json:"to,omitempty"`
+	// Webhook additional HTTP headers
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// NewNotifier creates notifier using given configuration
+func NewNotifier(c NotifierConfig) (Notifier, error) {
+	name := c.Name
+	if name == "" {
+		name = c.Type
+	}
+	switch c.Type {
+	case NotifierWebhook:
+		if c.Url == "" {
+			return nil, errors.New("webhook URL is not set")
+		}
+		return &WebhookNotifier{name: name, url: c.Url, headers: c.Headers}, nil
+	case NotifierSmtp:
+		if c.Host == "" || c.From == "" || len(c.To) == 0 {
+			return nil, errors.New("SMTP server host, sender or recipients are not set")
+		}
+		port := c.Port
+		if port == 0 {
+			port = 587
+		}
+		return &SmtpNotifier{name: name, host: c.Host, port: port, username: c.Username, password: c.Password,
+			from: c.From, to: c.To}, nil
+	case NotifierTelegram:
+		if c.Token == "" || c.ChatId == "" {
+			return nil, errors.New("Telegram bot token or chat ID is not set")
+		}
+		return &TelegramNotifier{name: name, token: c.Token, chatId: c.ChatId}, nil
+	case NotifierNtfy:
+		if c.Url == "" {
+			return nil, errors.New("ntfy topic URL is not set")
+		}
+		return &NtfyNotifier{name: name, url: c.Url, token: c.Token}, nil
+	}
+	return nil, fmt.Errorf("unknown notifier type: %s (known types: %s)", c.Type,
+		SliceToString(NotifierTypeList()))
+}
+
+// WebhookNotifier posts alert events as JSON to given URL
+type WebhookNotifier struct {
+	name    string
+	url     string
+	headers map[string]string
+}
+
+func (wn *WebhookNotifier) Name() string {
+	return wn.name
+}
+
+func (wn *WebhookNotifier) Notify(e *AlertEvent) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	headers := map[string]interface{}{"Content-Type": "application/json"}
+	for k, v := range wn.headers {
+		headers[k] = v
+	}
+	_, err = HttpPostData(wn.url, headers, b)
+	return err
+}
+
+// SmtpNotifier sends alert events by email
+type SmtpNotifier struct {
+	name     string
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+	to       []string
+}
+
+func (sn *SmtpNotifier) Name() string {
+	return sn.name
+}
+
+func (sn *SmtpNotifier) Notify(e *AlertEvent) error {
+	var auth smtp.Auth
+	if sn.username != "" {
+		auth = smtp.PlainAuth("", sn.username, sn.password, sn.host)
+	}
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\n"+
+		"Content-Type: text/plain; charset=utf-8\r\n\r\n%s\r\n",
+		sn.from, strings.Join(sn.to, ", "), e.Title(), e.Time.Format(time.RFC1123Z), e.Message)
+	return sn.sendMail(auth, []byte(msg))
+}
+
+// sendMail sends message like smtp.SendMail does but with connection and sending timeout
+func (sn *SmtpNotifier) sendMail(auth smtp.Auth, msg []byte) error {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(sn.host, strconv.Itoa(sn.port)), smtpTimeout)
+	if err != nil {
+		return err
+	}
+	if err := conn.SetDeadline(time.Now().Add(smtpTimeout)); err != nil {
+		CloseQuietly(conn)
+		return err
+	}
+	c, err := smtp.NewClient(conn, sn.host)
+	if err != nil {
+		CloseQuietly(conn)
+		return err
+	}
+	defer CloseQuietly(c)
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: sn.host}); err != nil {
+			return err
+		}
+	}
+	if auth != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("SMTP server doesn't support authentication")
+		}
+		if err := c.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(sn.from); err != nil {
+		return err
+	}
+	for _, to := range sn.to {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// TelegramNotifier sends alert events to Telegram chat using Bot API
+type TelegramNotifier struct {
+	name   string
+	token  string
+	chatId string
+}
+
+func (tn *TelegramNotifier) Name() string {
+	return tn.name
+}
+
+func (tn *TelegramNotifier) Notify(e *AlertEvent) error {
+	message := map[string]string{
+		"chat_id": tn.chatId,
+		"text":    fmt.Sprintf("%s\n%s", e.Title(), e.Message),
+	}
+	var r struct {
+		Ok          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err := HttpPostJson(fmt.Sprintf("%s/bot%s/sendMessage", telegramApiUrl, tn.token), nil, message,
+		&r); err != nil {
+		// Don't expose bot token in errors
+		return errors.New(strings.ReplaceAll(err.Error(), tn.token, "***"))
+	}
+	if !r.Ok {
+		return fmt.Errorf("Telegram API error: %s", r.Description)
+	}
+	return nil
+}
+
+// NtfyNotifier publishes alert events to ntfy topic
+// https://docs.ntfy.sh/publish/
+type NtfyNotifier struct {
+	name  string
+	url   string
+	token string
+}
+
+func (nn *NtfyNotifier) Name() string {
+	return nn.name
+}
+
+func (nn *NtfyNotifier) Notify(e *AlertEvent) error {
+	headers := map[string]interface{}{
+		"Title": e.Title(),
+		"Tags":  "warning",
+	}
+	if e.State == AlertStateResolved {
+		headers["Tags"] = "white_check_mark"
+	} else {
+		headers["Priority"] = "high"
+	}
+	if nn.token != "" {
+		headers["Authorization"] = "Bearer " + nn.token
+	}
+	_, err := HttpPostData(nn.url, headers, []byte(e.Message))
+	return err
+}
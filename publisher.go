@@ -19,6 +19,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
@@ -31,6 +32,13 @@ type Publisher interface {
 	Publish(data *StationData)
 }
 
+const (
+	// Server-Sent Events stream heartbeat comments interval
+	sseHeartbeatInterval = 15 * time.Second
+	// Server-Sent Events client buffer size (in events), slower clients are dropped
+	sseClientBufferSize = 16
+)
+
 type HttpPublisher struct {
 	sync.Mutex
 
@@ -40,24 +48,35 @@ type HttpPublisher struct {
 	serverStopWg *sync.WaitGroup
 
 	lastData *StationData
+
+	history   *History
+	logBuffer *LogBuffer
+	watchdog  *Watchdog
+
+	sseClients map[chan []byte]struct{}
+	stopped    bool
 }
 
-func NewHttpPublisher(port int) *HttpPublisher {
+func NewHttpPublisher(port int, history *History, logBuffer *LogBuffer, watchdog *Watchdog) *HttpPublisher {
 	return &HttpPublisher{
-		port: port,
+		port:       port,
+		history:    history,
+		logBuffer:  logBuffer,
+		watchdog:   watchdog,
+		sseClients: make(map[chan []byte]struct{}),
 	}
 }
 
 func (hp *HttpPublisher) Start() error {
-	log.Printf("starting sensor data HTTP publisher at http://0.0.0.0:%d/json", hp.port)
+	log.Printf("starting sensor data HTTP publisher at http://0.0.0.0:%d/", hp.port)
 	mux := http.NewServeMux()
 	mux.HandleFunc("/json", func(w http.ResponseWriter, r *http.Request) {
-		if hp.lastData == nil {
+		ld := hp.data()
+		if ld == nil {
 			w.WriteHeader(503)
 			return
 		}
-		ld := hp.lastData
-		ep := NewEspData(ld.LastMeasurement, ld.Uptime, ld.Version)
+		ep := NewEspData(ld)
 		jd, err := json.Marshal(ep)
 		if err != nil {
 			w.WriteHeader(500)
@@ -68,6 +87,25 @@ func (hp *HttpPublisher) Start() error {
 		w.Header().Set("Content-Type", "application/json")
 		w.Write(jd)
 	})
+	mux.HandleFunc("/data.json", func(w http.ResponseWriter, r *http.Request) {
+		ld := hp.data()
+		if ld == nil {
+			w.WriteHeader(503)
+			return
+		}
+		sd := NewAirrohrSensorData(ld)
+		if ld.LastMeasurement.Timestamp != nil {
+			age := time.Since(time.Time(*ld.LastMeasurement.Timestamp))
+			sd.Age = strconv.Itoa(int(age.Seconds()))
+		}
+		writeJson(w, sd)
+	})
+	mux.HandleFunc("/events", hp.handleEvents)
+	mux.Handle("/metrics", metrics)
+	if hp.watchdog != nil {
+		mux.Handle("/watchdog", hp.watchdog)
+	}
+	hp.registerDashboard(mux)
 	hp.server = &http.Server{Addr: fmt.Sprintf(":%d", hp.port), Handler: mux}
 	hp.serverStopWg = &sync.WaitGroup{}
 	hp.serverStopWg.Add(1)
@@ -82,6 +120,13 @@ func (hp *HttpPublisher) Start() error {
 
 func (hp *HttpPublisher) Stop() {
 	log.Print("stopping sensor data HTTP publisher...")
+	// Close event streams since server shutdown waits for active connections
+	hp.Lock()
+	hp.stopped = true
+	for c := range hp.sseClients {
+		hp.dropSseClient(c)
+	}
+	hp.Unlock()
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	if err := hp.server.Shutdown(ctx); err != nil {
@@ -96,4 +141,100 @@ func (hp *HttpPublisher) Publish(data *StationData) {
 	hp.Lock()
 	defer hp.Unlock()
 	hp.lastData = &lastData
+
+	if len(hp.sseClients) == 0 {
+		return
+	}
+
+	jd, err := json.Marshal(&lastData)
+	if err != nil {
+		log.Errorf("can't marshal station data event: %v", err)
+		return
+	}
+	for c := range hp.sseClients {
+		select {
+		case c <- jd:
+		default:
+			log.Warn("dropping slow sensor data event stream client")
+			hp.dropSseClient(c)
+		}
+	}
+}
+
+func (hp *HttpPublisher) data() *StationData {
+	hp.Lock()
+	defer hp.Unlock()
+	return hp.lastData
+}
+
+// handleEvents streams published station data as Server-Sent Events
+func (hp *HttpPublisher) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	c := make(chan []byte, sseClientBufferSize)
+
+	hp.Lock()
+	if hp.stopped {
+		hp.Unlock()
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	hp.sseClients[c] = struct{}{}
+	if hp.lastData != nil {
+		if jd, err := json.Marshal(hp.lastData); err == nil {
+			c <- jd
+		}
+	}
+	hp.Unlock()
+
+	defer func() {
+		hp.Lock()
+		if _, ok := hp.sseClients[c]; ok {
+			hp.dropSseClient(c)
+		}
+		hp.Unlock()
+	}()
+
+	log.Debugf("sensor data event stream client connected: %s", r.RemoteAddr)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		var err error
+		select {
+		case jd, ok := <-c:
+			if !ok {
+				log.Debugf("sensor data event stream client closed: %s", r.RemoteAddr)
+				return
+			}
+			_, err = fmt.Fprintf(w, "event: data\ndata: %s\n\n", jd)
+		case <-heartbeat.C:
+			_, err = fmt.Fprint(w, ": heartbeat\n\n")
+		case <-r.Context().Done():
+			log.Debugf("sensor data event stream client disconnected: %s", r.RemoteAddr)
+			return
+		}
+		if err != nil {
+			log.Debugf("sensor data event stream client write error: %v", err)
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// dropSseClient closes event stream client channel, must be called with publisher locked
+func (hp *HttpPublisher) dropSseClient(c chan []byte) {
+	delete(hp.sseClients, c)
+	close(c)
 }
@@ -0,0 +1,119 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openairtech/api"
+
+	"github.com/stretchr/testify/require"
+)
+
+// startTestHttpPublisher starts HTTP publisher on free port and returns it with given path response
+func startTestHttpPublisher(t *testing.T, path string) (*HttpPublisher, *http.Response) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+	require.NoError(t, l.Close())
+
+	hp := NewHttpPublisher(port, nil, nil, nil)
+	require.NoError(t, hp.Start())
+
+	var r *http.Response
+	require.Eventually(t, func() bool {
+		r, err = http.Get(fmt.Sprintf("http://127.0.0.1:%d%s", port, path))
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	return hp, r
+}
+
+func TestHttpPublisher_DataJson(t *testing.T) {
+	hp, r := startTestHttpPublisher(t, "/data.json")
+	defer hp.Stop()
+	CloseQuietly(r.Body)
+	require.Equal(t, http.StatusServiceUnavailable, r.StatusCode, "no data is published yet")
+
+	ts := api.UnixTime(time.Now().Add(-30 * time.Second))
+	pm25, pm10, temp := float32(12.34), float32(20), float32(21.46)
+	hp.Publish(&StationData{Version: "1.2", LastMeasurement: &api.Measurement{Timestamp: &ts, Pm25: &pm25,
+		Pm10: &pm10, Temperature: &temp}})
+
+	r, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/data.json", hp.port))
+	require.NoError(t, err)
+	defer CloseQuietly(r.Body)
+	require.Equal(t, http.StatusOK, r.StatusCode)
+
+	var got map[string]interface{}
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+	require.Equal(t, "1.2", got["software_version"])
+	age, err := strconv.Atoi(got["age"].(string))
+	require.NoError(t, err)
+	require.InDelta(t, 30, age, 5)
+	// Absent humidity and pressure values are omitted
+	require.Equal(t, []interface{}{
+		map[string]interface{}{"value_type": "SDS_P1", "value": float64(20)},
+		map[string]interface{}{"value_type": "SDS_P2", "value": 12.3},
+		map[string]interface{}{"value_type": "BME280_temperature", "value": 21.5},
+	}, got["sensordatavalues"])
+}
+
+func TestHttpPublisher_Events(t *testing.T) {
+	hp, r := startTestHttpPublisher(t, "/events")
+	defer CloseQuietly(r.Body)
+	require.Equal(t, "text/event-stream", r.Header.Get("Content-Type"))
+
+	data := testHistoryData(time.Unix(1600000000, 0), 12.5)
+	data.TokenId = "token"
+	data.Uptime = 90 * time.Second
+	hp.Publish(data)
+
+	sc := bufio.NewScanner(r.Body)
+	var lines []string
+	for sc.Scan() && sc.Text() != "" {
+		lines = append(lines, sc.Text())
+	}
+	require.Len(t, lines, 2)
+	require.Equal(t, "event: data", lines[0])
+
+	var got map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(lines[1], "data: ")), &got))
+	require.Equal(t, "token", got["token_id"])
+	require.Equal(t, float64(90), got["uptime"])
+
+	hp.Lock()
+	require.Len(t, hp.sseClients, 1)
+	var c chan []byte
+	for c = range hp.sseClients {
+	}
+	hp.Unlock()
+
+	// Stopped publisher closes event streams, so server shutdown doesn't wait for them
+	start := time.Now()
+	hp.Stop()
+	require.Less(t, int64(time.Since(start)), int64(time.Second))
+	_, ok := <-c
+	require.False(t, ok)
+	require.Empty(t, hp.sseClients)
+	require.False(t, sc.Scan())
+}
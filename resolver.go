@@ -21,33 +21,52 @@ import (
 	"net"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/grandcat/zeroconf"
 	log "github.com/sirupsen/logrus"
 )
 
-func InitResolvers(timeout time.Duration) {
+const (
+	// Resolved address time to live if not provided by mDNS responder
+	resolverDefaultTtl = 2 * time.Minute
+)
+
+var localResolver *LocalResolver
+
+func InitResolvers(timeout, refreshInterval time.Duration) {
 	dialer := &net.Dialer{
 		Timeout: timeout,
 	}
 
+	localResolver = NewLocalResolver(timeout, refreshInterval)
+
 	http.DefaultTransport.(*http.Transport).DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
 		log.Debugf("dialing: %s", addr)
 
 		host, port := ParseAddr(addr)
 
-		if isLocalDomain(host) {
-			h, err := resolveLocalDomain(host, timeout)
-			if err == nil {
-				addr = fmt.Sprintf("%s:%s", h, port)
-				log.Debugf("resolved: [%s] -> [%s]", host, h)
-			} else {
-				log.Errorf("can't resolve %s: %v", addr, err)
+		if !isLocalDomain(host) {
+			return dialer.DialContext(ctx, network, addr)
+		}
+
+		ips, err := localResolver.Resolve(host)
+		if err != nil {
+			log.Errorf("can't resolve %s: %v", addr, err)
+			return dialer.DialContext(ctx, network, addr)
+		}
+
+		log.Debugf("resolved: [%s] -> %v", host, ips)
+
+		var conn net.Conn
+		for _, ip := range ips {
+			if conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port)); err == nil {
+				return conn, nil
 			}
 		}
 
-		return dialer.DialContext(ctx, network, addr)
+		return nil, err
 	}
 }
 
@@ -55,10 +74,117 @@ func isLocalDomain(host string) bool {
 	return strings.HasSuffix(host, ".local")
 }
 
-func resolveLocalDomain(host string, timeout time.Duration) (string, error) {
+type resolvedHost struct {
+	addrs   []net.IP
+	expires time.Time
+}
+
+// LocalResolver resolves .local host names using mDNS. Resolved addresses are cached
+// for their TTL and refreshed in background, last known good addresses are used
+// when host can't be resolved
+type LocalResolver struct {
+	sync.Mutex
+
+	timeout         time.Duration
+	refreshInterval time.Duration
+	lookup          func(host string, timeout time.Duration) ([]net.IP, time.Duration, error)
+
+	hosts map[string]*resolvedHost
+}
+
+// NewLocalResolver creates resolver with given lookup timeout and background refresh
+// interval (zero to disable background refresh)
+func NewLocalResolver(timeout, refreshInterval time.Duration) *LocalResolver {
+	return &LocalResolver{
+		timeout:         timeout,
+		refreshInterval: refreshInterval,
+		lookup:          lookupLocalDomain,
+		hosts:           make(map[string]*resolvedHost),
+	}
+}
+
+// Resolve returns given host addresses, IPv4 addresses first
+func (lr *LocalResolver) Resolve(host string) ([]net.IP, error) {
+	lr.Lock()
+	rh, ok := lr.hosts[host]
+	lr.Unlock()
+
+	if ok && time.Now().Before(rh.expires) {
+		return rh.addrs, nil
+	}
+
+	addrs, err := lr.update(host)
+	if err != nil {
+		if ok {
+			log.Warnf("can't resolve %s, using last known address(es) %v: %v", host, rh.addrs, err)
+			return rh.addrs, nil
+		}
+		return nil, err
+	}
+
+	return addrs, nil
+}
+
+// Invalidate makes given host name to be resolved again on next request
+func (lr *LocalResolver) Invalidate(host string) {
+	lr.Lock()
+	defer lr.Unlock()
+	if rh, ok := lr.hosts[host]; ok {
+		lr.hosts[host] = &resolvedHost{addrs: rh.addrs}
+	}
+}
+
+func (lr *LocalResolver) update(host string) ([]net.IP, error) {
+	addrs, ttl, err := lr.lookup(host, lr.timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	lr.Lock()
+	defer lr.Unlock()
+
+	rh, ok := lr.hosts[host]
+	if !ok {
+		if lr.refreshInterval > 0 {
+			go lr.refresh(host)
+		}
+	} else if !equalAddrs(rh.addrs, addrs) {
+		log.Infof("%s address changed: %v -> %v", host, rh.addrs, addrs)
+		// Idle connections could be connected to outdated host address
+		http.DefaultTransport.(*http.Transport).CloseIdleConnections()
+	}
+
+	lr.hosts[host] = &resolvedHost{addrs: addrs, expires: time.Now().Add(ttl)}
+
+	return addrs, nil
+}
+
+func (lr *LocalResolver) refresh(host string) {
+	ticker := time.NewTicker(lr.refreshInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if _, err := lr.update(host); err != nil {
+			log.Debugf("can't refresh %s address: %v", host, err)
+		}
+	}
+}
+
+func equalAddrs(a, b []net.IP) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func lookupLocalDomain(host string, timeout time.Duration) ([]net.IP, time.Duration, error) {
 	resolver, err := zeroconf.NewResolver(nil)
 	if err != nil {
-		return "", fmt.Errorf("can't create resolver: %v", err)
+		return nil, 0, fmt.Errorf("can't create resolver: %v", err)
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
@@ -69,14 +195,40 @@ func resolveLocalDomain(host string, timeout time.Duration) (string, error) {
 
 	err = resolver.Lookup(ctx, strings.TrimSuffix(host, ".local"), "_http._tcp", "local.", entries)
 	if err != nil {
-		return "", err
+		return nil, 0, err
 	}
 
 	entry := <-entries
 
-	if entry != nil {
-		return entry.AddrIPv4[0].String(), nil
+	// Drain entries channel until it is closed on lookup context cancellation
+	go func() {
+		for range entries {
+		}
+	}()
+
+	if entry == nil {
+		return nil, 0, errors.New("resolver timeout: " + host)
 	}
 
-	return "", errors.New("resolver timeout: " + host)
+	addrs := append(append([]net.IP{}, entry.AddrIPv4...), entry.AddrIPv6...)
+	if len(addrs) == 0 {
+		return nil, 0, errors.New("no addresses resolved: " + host)
+	}
+
+	ttl := time.Duration(entry.TTL) * time.Second
+	if ttl == 0 {
+		ttl = resolverDefaultTtl
+	}
+
+	return addrs, ttl, nil
+}
+
+// ReresolveHost makes given host name to be resolved again on next connection
+func ReresolveHost(host string) {
+	log.Debugf("re-resolving %s", host)
+	if localResolver != nil && isLocalDomain(host) {
+		localResolver.Invalidate(host)
+	}
+	// Idle connections could be connected to outdated host address
+	http.DefaultTransport.(*http.Transport).CloseIdleConnections()
 }
@@ -0,0 +1,71 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalResolver(t *testing.T) {
+	lr := NewLocalResolver(time.Second, 0)
+	lookups := 0
+	var addrs []net.IP
+	ttl := time.Hour
+	var lookupErr error
+	lr.lookup = func(host string, timeout time.Duration) ([]net.IP, time.Duration, error) {
+		lookups++
+		return addrs, ttl, lookupErr
+	}
+
+	lookupErr = errors.New("resolver timeout")
+	_, err := lr.Resolve("openair.local")
+	require.Error(t, err)
+
+	lookupErr = nil
+	addrs = []net.IP{net.ParseIP("192.168.1.10"), net.ParseIP("fe80::1")}
+	ips, err := lr.Resolve("openair.local")
+	require.NoError(t, err)
+	require.Equal(t, addrs, ips)
+	require.Equal(t, 2, lookups)
+
+	// Cached address is used until TTL expires
+	_, err = lr.Resolve("openair.local")
+	require.NoError(t, err)
+	require.Equal(t, 2, lookups)
+
+	// Last known good address is used if host can't be resolved
+	lr.Invalidate("openair.local")
+	lookupErr = errors.New("resolver timeout")
+	ips, err = lr.Resolve("openair.local")
+	require.NoError(t, err)
+	require.Equal(t, addrs, ips)
+	require.Equal(t, 3, lookups)
+
+	// Expired address is resolved again
+	lookupErr = nil
+	ttl = 0
+	addrs = []net.IP{net.ParseIP("192.168.1.11")}
+	ips, err = lr.Resolve("openair.local")
+	require.NoError(t, err)
+	require.Equal(t, addrs, ips)
+	_, err = lr.Resolve("openair.local")
+	require.NoError(t, err)
+	require.Equal(t, 5, lookups)
+}
@@ -16,9 +16,9 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"math"
 	"strings"
 	"sync"
 	"time"
@@ -39,8 +39,8 @@ import (
 const (
 	// System epoch time (2019-01-01 GMT) as an Unix time
 	systemEpoch = 1546300800
-	// Heater disabling humidity hysteresis (in percents)
-	heaterDisableHumidityHysteresis = 5
+	// ESP station heater pin state reconcile interval
+	espHeaterReconcileInterval = 5 * time.Minute
 )
 
 type HeaterState bool
@@ -51,10 +51,73 @@ const (
 )
 
 type StationData struct {
-	Version         string
-	TokenId         string
-	Uptime          time.Duration
-	LastMeasurement *api.Measurement
+	Version         string               `json:"version"`
+	TokenId         string               `json:"token_id"`
+	MacAddress      string               `json:"mac_address,omitempty"`
+	Uptime          time.Duration        `json:"-"`
+	LastMeasurement *api.Measurement     `json:"-"`
+	RawMeasurement  *api.Measurement     `json:"-"`
+	HeaterState     HeaterState          `json:"heater"`
+	HeaterControl   *HeaterControlStatus `json:"heater_control,omitempty"`
+	Quality         []QualityFlag        `json:"quality,omitempty"`
+	Derived         *DerivedValues       `json:"derived,omitempty"`
+	Averages        *PmAveragesStatus    `json:"averages,omitempty"`
+	Watchdog        *WatchdogStatus      `json:"watchdog,omitempty"`
+	Feeders         []FeederStatus       `json:"feeders,omitempty"`
+	Device          *DeviceStatus        `json:"device,omitempty"`
+}
+
+// DeviceStatus is the station device system status, unknown values are omitted
+type DeviceStatus struct {
+	Firmware    string   `json:"firmware,omitempty"`
+	Rssi        *int     `json:"rssi,omitempty"`
+	FreeRam     *int     `json:"free_ram,omitempty"`
+	Load        *float32 `json:"load,omitempty"`
+	ResetReason string   `json:"reset_reason,omitempty"`
+}
+
+// MeasurementJson is the station measurement JSON representation
+// independent of OpenAir API wire format
+type MeasurementJson struct {
+	Timestamp   int64    `json:"timestamp,omitempty"`
+	Temperature *float32 `json:"temperature,omitempty"`
+	Humidity    *float32 `json:"humidity,omitempty"`
+	Pressure    *float32 `json:"pressure,omitempty"`
+	Pm25        *float32 `json:"pm25,omitempty"`
+	Pm10        *float32 `json:"pm10,omitempty"`
+}
+
+func NewMeasurementJson(m *api.Measurement) *MeasurementJson {
+	if m == nil {
+		return nil
+	}
+	mj := &MeasurementJson{
+		Temperature: m.Temperature,
+		Humidity:    m.Humidity,
+		Pressure:    m.Pressure,
+		Pm25:        m.Pm25,
+		Pm10:        m.Pm10,
+	}
+	if m.Timestamp != nil {
+		mj.Timestamp = time.Time(*m.Timestamp).Unix()
+	}
+	return mj
+}
+
+// MarshalJSON marshals station data with uptime in seconds
+func (sd StationData) MarshalJSON() ([]byte, error) {
+	type stationData StationData
+	return json.Marshal(struct {
+		stationData
+		Uptime         int64            `json:"uptime"`
+		Measurement    *MeasurementJson `json:"measurement"`
+		RawMeasurement *MeasurementJson `json:"raw_measurement,omitempty"`
+	}{
+		stationData:    stationData(sd),
+		Uptime:         int64(sd.Uptime.Seconds()),
+		Measurement:    NewMeasurementJson(sd.LastMeasurement),
+		RawMeasurement: NewMeasurementJson(sd.RawMeasurement),
+	})
 }
 
 type Station interface {
@@ -74,18 +137,29 @@ type EspStation struct {
 
 	tokenId string
 
-	heaterPin   int
-	heaterState HeaterState
+	mapping *EspMapping
 
-	lastUptime *time.Duration
+	heaterPin int
+	// Observed heater pin state
+	heaterState HeaterState
+	// Last requested heater state
+	heaterRequested HeaterState
+	// Heater state is requested at least once and should be reconciled with actual pin state
+	heaterSet           bool
+	heaterReconcileTime time.Time
+
+	lastUptime   *time.Duration
+	lastFirmware string
 }
 
-func NewEspStation(version, host string, port int, heaterPin int, tokenId string) *EspStation {
+// NewEspStation creates ESP Easy station with heater disabled if heater GPIO pin is negative
+func NewEspStation(version, host string, port int, heaterPin int, tokenId string, mapping *EspMapping) *EspStation {
 	return &EspStation{
 		version:   version,
 		host:      host,
 		port:      port,
 		tokenId:   tokenId,
+		mapping:   mapping,
 		heaterPin: heaterPin,
 	}
 }
@@ -95,6 +169,14 @@ func (es *EspStation) Version() string {
 }
 
 func (es *EspStation) Start() error {
+	if es.heaterPin >= 0 {
+		if state, err := es.readHeaterState(); err != nil {
+			log.Errorf("can't read heater pin %d state: %v", es.heaterPin, err)
+		} else {
+			es.heaterState = state
+			log.Debugf("heater pin %d state: %d", es.heaterPin, pinState(state))
+		}
+	}
 	log.Print("started ESP station")
 	return nil
 }
@@ -108,15 +190,29 @@ func (es *EspStation) HeaterState() HeaterState {
 }
 
 func (es *EspStation) TurnHeater(state HeaterState) {
-	pinState := 0
-	if state == HeaterOn {
-		pinState = 1
+	if es.heaterPin < 0 {
+		log.Error("can't turn heater: ESP heater GPIO pin is not set")
+		return
 	}
 
-	url := fmt.Sprintf("http://%s:%d/control?cmd=GPIO,%d,%d", es.host, es.port, es.heaterPin, pinState)
+	// Requested state is reconciled later if request fails
+	es.heaterRequested = state
+	es.heaterSet = true
+
+	url := fmt.Sprintf("http://%s:%d/control?cmd=GPIO,%d,%d", es.host, es.port, es.heaterPin, pinState(state))
 	var response EspGpioControlResponse
 	if err := HttpGetData(url, &response); err != nil {
-		log.Errorf("can't set heater pin %d state %d: %v", es.heaterPin, pinState, err)
+		log.Errorf("can't set heater pin %d state %d: %v", es.heaterPin, pinState(state), err)
+		return
+	}
+
+	es.heaterReconcileTime = time.Now()
+
+	if response.State != pinState(state) {
+		log.Warnf("heater pin %d state %d doesn't match requested state %d", es.heaterPin, response.State,
+			pinState(state))
+		metricHeaterMismatches.Inc()
+		es.heaterState = response.State != 0
 		return
 	}
 
@@ -129,6 +225,34 @@ func (es *EspStation) TurnHeater(state HeaterState) {
 	}
 }
 
+// readHeaterState reads actual heater pin state
+func (es *EspStation) readHeaterState() (HeaterState, error) {
+	url := fmt.Sprintf("http://%s:%d/control?cmd=status,gpio,%d", es.host, es.port, es.heaterPin)
+	var response EspGpioControlResponse
+	if err := HttpGetData(url, &response); err != nil {
+		return HeaterOff, err
+	}
+	return response.State != 0, nil
+}
+
+// reconcileHeater checks actual heater pin state matches last requested state and sets it again if not
+func (es *EspStation) reconcileHeater() {
+	es.heaterReconcileTime = time.Now()
+	actual, err := es.readHeaterState()
+	if err != nil {
+		log.Errorf("can't read heater pin %d state: %v", es.heaterPin, err)
+		return
+	}
+	es.heaterState = actual
+	if actual == es.heaterRequested {
+		return
+	}
+	log.Warnf("heater pin %d state %d doesn't match requested state %d, setting it again", es.heaterPin,
+		pinState(actual), pinState(es.heaterRequested))
+	metricHeaterMismatches.Inc()
+	es.TurnHeater(es.heaterRequested)
+}
+
 func (es *EspStation) GetData() (*StationData, error) {
 	url := fmt.Sprintf("http://%s:%d/json", es.host, es.port)
 
@@ -142,33 +266,57 @@ func (es *EspStation) GetData() (*StationData, error) {
 
 	log.Debugf("received sensor data: %+v", data)
 
-	m := data.Measurement(api.UnixTime(time.Now()))
+	m := data.MappedMeasurement(api.UnixTime(time.Now()), es.mapping)
+
+	macAddress := data.WiFi.MacAddress()
 
 	var tokenId string
 	if es.tokenId != "" {
 		tokenId = es.tokenId
 	} else {
-		tokenId = stationTokenId(data.WiFi.MacAddress())
+		tokenId = stationTokenId(macAddress)
 	}
 	log.Debugf("token ID: %s", tokenId)
 
-	uptime := time.Duration(data.System.Uptime) * time.Minute
+	if data.System == nil {
+		return nil, errors.New("no system information in ESP station data")
+	}
+
+	if firmware := data.System.FirmwareVersion(); firmware != es.lastFirmware {
+		log.Infof("ESP station firmware: %s", firmware)
+		if rd := data.System.ReleaseDate(); !rd.IsZero() && rd.Before(espMinFirmwareReleaseDate) {
+			log.Warnf("ESP station firmware is older than oldest supported release (%s)",
+				espMinFirmwareReleaseDate.Format("20060102"))
+		}
+		es.lastFirmware = firmware
+	}
+
+	uptime := data.System.UptimeDuration()
 
-	if es.lastUptime != nil && uptime < *es.lastUptime {
+	rebooted := es.lastUptime != nil && uptime < *es.lastUptime
+	if rebooted {
 		log.Warn("ESP station reboot detected")
-		es.heaterState = HeaterOff
 	}
 
 	es.lastUptime = &uptime
 
+	// Pin state is reset on station reboot
+	if es.heaterSet && (rebooted || time.Since(es.heaterReconcileTime) >= espHeaterReconcileInterval) {
+		es.reconcileHeater()
+	}
+
 	return &StationData{
 		Version:         es.version,
 		TokenId:         tokenId,
+		MacAddress:      macAddress,
 		Uptime:          uptime,
 		LastMeasurement: m,
+		Device:          data.DeviceStatus(),
 	}, nil
 }
 
+// RpiStation reads BME280 sensor connected to I2C bus and SDS011 sensor connected to serial port
+// and controls heater using GPIO line, every component is optional so station can run on any Linux host
 type RpiStation struct {
 	version string
 
@@ -177,8 +325,9 @@ type RpiStation struct {
 	sdsSensorPort     string
 	sdsSensorInterval int
 
-	startTime time.Time
-	tokenId   string
+	startTime  time.Time
+	tokenId    string
+	macAddress string
 
 	i2cBus     *i2c.I2C
 	serialPort serial.Port
@@ -187,22 +336,42 @@ type RpiStation struct {
 	sdsSensor *sds011.Sensor
 
 	pmLock sync.RWMutex
-	pm25   float32
-	pm10   float32
-
-	heaterPin   int
-	heaterState HeaterState
+	pm25   *float32
+	pm10   *float32
+
+	gpioChipName string
+	gpioChip     GpioChip
+	heaterLineId int
+	heaterLine   GpioLine
+	heaterState  HeaterState
 }
 
+// NewRpiStation creates station with BME280 sensor disabled if I2C bus ID is negative,
+// SDS011 sensor disabled if serial port name is empty and heater disabled if heater GPIO line is negative.
+// Station token ID (if not given) is generated from MAC address of given network interface
+// (or first wireless interface if interface name is empty) or from machine ID if there is no MAC address.
 func NewRpiStation(version string, i2cBusId int, bmeSensorAddress int, sdsSensorPort string, sdsSensorInterval int,
-	heaterPin int, tokenId string) (*RpiStation, error) {
+	gpioChip string, heaterLine int, macInterface, tokenId string) (*RpiStation, error) {
+	if i2cBusId < 0 && sdsSensorPort == "" {
+		return nil, errors.New("neither BME280 nor SDS011 sensor is enabled")
+	}
+
+	var macAddress string
+	if macInterface != "" {
+		macAddress = InterfaceMacAddr(macInterface)
+	} else {
+		macAddress = WirelessInterfaceMacAddr()
+	}
+	log.Debugf("MAC address: %s", macAddress)
 	if tokenId == "" {
-		macAddress := WirelessInterfaceMacAddr()
-		if macAddress == "" {
-			return nil, errors.New("can't determine RPi station MAC address")
+		if macAddress != "" {
+			tokenId = stationTokenId(macAddress)
+		} else if machineId := MachineId(); machineId != "" {
+			log.Debugf("machine ID: %s", machineId)
+			tokenId = Sha1(machineId)
+		} else {
+			return nil, errors.New("can't determine station MAC address or machine ID")
 		}
-		log.Debugf("MAC address: %s", macAddress)
-		tokenId = stationTokenId(macAddress)
 	}
 	log.Debugf("token ID: %s", tokenId)
 
@@ -210,11 +379,13 @@ func NewRpiStation(version string, i2cBusId int, bmeSensorAddress int, sdsSensor
 		version:           version,
 		startTime:         time.Now(),
 		tokenId:           tokenId,
+		macAddress:        macAddress,
 		i2cBusId:          i2cBusId,
 		bmeSensorAddress:  bmeSensorAddress,
 		sdsSensorPort:     sdsSensorPort,
 		sdsSensorInterval: sdsSensorInterval,
-		heaterPin:         heaterPin,
+		gpioChipName:      gpioChip,
+		heaterLineId:      heaterLine,
 	}, nil
 }
 
@@ -225,29 +396,61 @@ func (rs *RpiStation) Version() string {
 func (rs *RpiStation) Start() error {
 	log.Print("starting RPi station...")
 
-	// Init BME280 sensor I2C bus
-	if err := rs.initI2cBus(); err != nil {
-		return fmt.Errorf("I2C bus init error: %v", err)
-	}
+	if rs.i2cBusId >= 0 {
+		// Init BME280 sensor I2C bus
+		if err := rs.initI2cBus(); err != nil {
+			return fmt.Errorf("I2C bus init error: %v", err)
+		}
 
-	// Init BME280 sensor
-	if err := rs.initBmeSensor(); err != nil {
-		return fmt.Errorf("BME280 sensor init error: %v", err)
+		// Init BME280 sensor
+		if err := rs.initBmeSensor(); err != nil {
+			return fmt.Errorf("BME280 sensor init error: %v", err)
+		}
+	} else {
+		log.Print("BME280 sensor is disabled")
 	}
 
-	// Open SDS011 sensor serial port
-	if err := rs.initSerialPort(); err != nil {
-		return fmt.Errorf("serial port init error: %v", err)
+	if rs.sdsSensorPort != "" {
+		// Open SDS011 sensor serial port
+		if err := rs.initSerialPort(); err != nil {
+			return fmt.Errorf("serial port init error: %v", err)
+		}
+
+		// Init SDS011 sensor
+		if err := rs.initSdsSensor(); err != nil {
+			return fmt.Errorf("SDS011 sensor init error: %v", err)
+		}
+
+		// Start SDS011 sensor data reading
+		go rs.readSdsSensor()
+	} else {
+		log.Print("SDS011 sensor is disabled")
 	}
 
-	// Init SDS011 sensor
-	if err := rs.initSdsSensor(); err != nil {
-		return fmt.Errorf("SDS011 sensor init error: %v", err)
+	if rs.heaterLineId >= 0 {
+		if err := rs.initHeaterLine(); err != nil {
+			return fmt.Errorf("heater GPIO line init error: %v", err)
+		}
 	}
 
-	// Start SDS011 sensor data reading
-	go rs.readSdsSensor()
+	return nil
+}
 
+func (rs *RpiStation) initHeaterLine() error {
+	var err error
+	if rs.gpioChip, err = OpenGpioChip(rs.gpioChipName); err != nil {
+		return err
+	}
+	if rs.heaterLine, err = rs.gpioChip.OutputLine(rs.heaterLineId); err != nil {
+		return err
+	}
+	// Read back actual heater state, line value could be kept from previous run
+	v, err := rs.heaterLine.Value()
+	if err != nil {
+		return err
+	}
+	rs.heaterState = v != 0
+	log.Printf("heater GPIO line %s:%d value: %d", rs.gpioChipName, rs.heaterLineId, v)
 	return nil
 }
 
@@ -304,9 +507,9 @@ func (rs *RpiStation) readSdsSensor() {
 			_ = rs.flushSerialPort()
 			continue
 		}
+		pm25, pm10 := float32(point.PM25), float32(point.PM10)
 		rs.pmLock.Lock()
-		rs.pm25 = float32(point.PM25)
-		rs.pm10 = float32(point.PM10)
+		rs.pm25, rs.pm10 = &pm25, &pm10
 		rs.pmLock.Unlock()
 		log.Debugf("read SDS011 sensor values, PM2.5: %v, PM10: %v", point.PM25, point.PM10)
 	}
@@ -314,8 +517,20 @@ func (rs *RpiStation) readSdsSensor() {
 
 func (rs *RpiStation) Stop() {
 	log.Print("stopping RPi station...")
-	_ = rs.i2cBus.Close()
-	rs.sdsSensor.Close()
+	if rs.i2cBus != nil {
+		_ = rs.i2cBus.Close()
+	}
+	if rs.sdsSensor != nil {
+		rs.sdsSensor.Close()
+	}
+	if rs.heaterLine != nil {
+		_ = rs.heaterLine.Close()
+		rs.heaterLine = nil
+	}
+	if rs.gpioChip != nil {
+		_ = rs.gpioChip.Close()
+		rs.gpioChip = nil
+	}
 }
 
 func (rs *RpiStation) HeaterState() HeaterState {
@@ -323,19 +538,17 @@ func (rs *RpiStation) HeaterState() HeaterState {
 }
 
 func (rs *RpiStation) TurnHeater(state HeaterState) {
-	cmdPinMode := fmt.Sprintf("gpio -1 mode %d out", rs.heaterPin)
-	if err := Execute(cmdPinMode, 5*time.Second); err != nil {
-		log.Errorf("can't set heater pin %d output mode: %v", rs.heaterPin, err)
+	if rs.heaterLine == nil {
+		log.Error("can't turn heater: heater GPIO line is not set")
 		return
 	}
 
-	pinState := 0
+	value := 0
 	if state == HeaterOn {
-		pinState = 1
+		value = 1
 	}
-	cmdPinState := fmt.Sprintf("gpio -1 write %d %d", rs.heaterPin, pinState)
-	if err := Execute(cmdPinState, 5*time.Second); err != nil {
-		log.Errorf("can't set heater pin %d state %d: %v", rs.heaterPin, pinState, err)
+	if err := rs.heaterLine.SetValue(value); err != nil {
+		log.Errorf("can't set heater GPIO line %d value %d: %v", rs.heaterLineId, value, err)
 		return
 	}
 
@@ -351,54 +564,72 @@ func (rs *RpiStation) TurnHeater(state HeaterState) {
 func (rs *RpiStation) GetData() (*StationData, error) {
 	timestamp := api.UnixTime(time.Now())
 
-	// Read temperature in Celsius degree
-	temperature, err := rs.bmeSensor.ReadTemperatureC(bsbmp.ACCURACY_STANDARD)
-	if err != nil {
-		return nil, err
+	m := &api.Measurement{
+		Timestamp: &timestamp,
 	}
 
-	// Read relative humidity
-	_, humidity, err := rs.bmeSensor.ReadHumidityRH(bsbmp.ACCURACY_STANDARD)
-	if err != nil {
-		return nil, err
-	}
+	if rs.bmeSensor != nil {
+		// Read temperature in Celsius degree
+		temperature, err := rs.bmeSensor.ReadTemperatureC(bsbmp.ACCURACY_STANDARD)
+		if err != nil {
+			return nil, err
+		}
 
-	// Read pressure in Pa
-	pressure, err := rs.bmeSensor.ReadPressurePa(bsbmp.ACCURACY_STANDARD)
-	if err != nil {
-		return nil, err
+		// Read relative humidity
+		_, humidity, err := rs.bmeSensor.ReadHumidityRH(bsbmp.ACCURACY_STANDARD)
+		if err != nil {
+			return nil, err
+		}
+
+		// Read pressure in Pa
+		pressure, err := rs.bmeSensor.ReadPressurePa(bsbmp.ACCURACY_STANDARD)
+		if err != nil {
+			return nil, err
+		}
+		// Convert pressure to hPa
+		pressure /= 100
+
+		m.Temperature = &temperature
+		m.Humidity = &humidity
+		m.Pressure = &pressure
 	}
-	// Convert pressure to hPa
-	pressure /= 100
 
+	// Copy values since measurement values could be modified later
 	rs.pmLock.RLock()
-	pm25 := rs.pm25
-	pm10 := rs.pm10
-	rs.pmLock.RUnlock()
-
-	m := &api.Measurement{
-		Timestamp:   &timestamp,
-		Temperature: &temperature,
-		Humidity:    &humidity,
-		Pressure:    &pressure,
-		Pm25:        &pm25,
-		Pm10:        &pm10,
-		Aqi:         nil,
+	if rs.pm25 != nil {
+		pm25 := *rs.pm25
+		m.Pm25 = &pm25
 	}
+	if rs.pm10 != nil {
+		pm10 := *rs.pm10
+		m.Pm10 = &pm10
+	}
+	rs.pmLock.RUnlock()
 
 	return &StationData{
 		Version:         rs.version,
 		TokenId:         rs.tokenId,
+		MacAddress:      rs.macAddress,
 		Uptime:          time.Since(rs.startTime),
 		LastMeasurement: m,
 	}, nil
 }
 
 func RunStation(ctx context.Context, station Station, feeders []Feeder, publishers []Publisher,
-	updateInterval time.Duration, settleTime time.Duration, disablePmCorrection, enableHeater bool,
-	heaterTurnOnHumidity int) {
+	updateInterval time.Duration, settleTime time.Duration, diagnostics *Diagnostics, filter *MeasurementFilter,
+	pmCorrection *PmCorrectionProfile, heater *HeaterController, altitude *float64, averages *PmAverages,
+	watchdog *Watchdog) {
 	p := time.Duration(0)
 
+	// Quality flags of measurements averaged into one
+	var quality []QualityFlag
+
+	// Several station measurements are averaged into one in update interval
+	pollInterval := updateInterval
+	if filter != nil {
+		pollInterval /= time.Duration(filter.Samples())
+	}
+
 	for _, publisher := range publishers {
 		publisher.Start()
 	}
@@ -408,14 +639,14 @@ func RunStation(ctx context.Context, station Station, feeders []Feeder, publishe
 		return
 	}
 
-	// Turn heater off at startup and at exit, if it's enabled
-	if enableHeater {
-		station.TurnHeater(HeaterOff)
+	defer station.Stop()
+
+	// Turn heater off at startup and at exit (before station is stopped), if it's enabled
+	if heater != nil {
+		heater.Start(station)
 		defer station.TurnHeater(HeaterOff)
 	}
 
-	defer station.Stop()
-
 	for _, publisher := range publishers {
 		defer publisher.Stop()
 	}
@@ -423,31 +654,52 @@ func RunStation(ctx context.Context, station Station, feeders []Feeder, publishe
 	for {
 		select {
 		case <-time.After(p):
-			p = updateInterval
+			p = pollInterval
 
 			data, err := station.GetData()
 			if err != nil {
-				log.Errorf("station data request failed: %v", err)
+				if watchdog != nil {
+					watchdog.Failure(time.Now(), err)
+				} else {
+					log.Errorf("station data request failed: %v", err)
+				}
 				continue
 			}
 
-			m := data.LastMeasurement
+			if watchdog != nil {
+				watchdog.Success(time.Now())
+				data.Watchdog = watchdog.Status()
+			}
+
+			if diagnostics != nil {
+				quality = MergeQualityFlags(quality, diagnostics.Diagnose(data.LastMeasurement, time.Now()))
+			}
 
-			if enableHeater && m.Humidity != nil {
-				humidity := int(*m.Humidity)
-				if station.HeaterState() == HeaterOff {
-					if humidity >= heaterTurnOnHumidity {
-						log.Infof("turning heater ON (humidity: %d%%)", humidity)
-						station.TurnHeater(HeaterOn)
-					}
-				} else if humidity <= heaterTurnOnHumidity-heaterDisableHumidityHysteresis {
-					log.Infof("turning heater OFF (humidity: %d%%)", humidity)
-					station.TurnHeater(HeaterOff)
+			if filter != nil {
+				m, ok := filter.Add(data.LastMeasurement)
+				if !ok {
+					continue
 				}
-			} else if !disablePmCorrection {
-				correctPm(m)
+				data.LastMeasurement = m
+			}
+
+			data.Quality, quality = quality, nil
+
+			m := data.LastMeasurement
+			raw := *m
+			data.RawMeasurement = &raw
+
+			if heater != nil {
+				now := time.Now()
+				heater.Control(station, m, now)
+				data.HeaterControl = heater.Status(now)
+			} else if pmCorrection != nil {
+				pmCorrection.Correct(m)
 			}
 
+			data.Derived = NewDerivedValues(m, altitude)
+			data.Derived.updateMetrics()
+
 			log.Debugf("temperature: %s, humidity: %s, pressure: %s, pm2.5: %s, pm10: %s",
 				Float32RefToString(m.Temperature), Float32RefToString(m.Humidity), Float32RefToString(m.Pressure),
 				Float32RefToString(m.Pm25), Float32RefToString(m.Pm10))
@@ -463,8 +715,18 @@ func RunStation(ctx context.Context, station Station, feeders []Feeder, publishe
 				continue
 			}
 
+			data.HeaterState = station.HeaterState()
+
+			feedData := data
+			if averages != nil {
+				averages.Add(m)
+				data.Averages = averages.Status(time.Now())
+				feedData = averages.FeedData(data)
+			}
+
 			for _, feeder := range feeders {
-				feeder.Feed(data)
+				feeder.Feed(feedData)
+				data.Feeders = append(data.Feeders, feeder.Status())
 			}
 
 			for _, publisher := range publishers {
@@ -477,24 +739,14 @@ func RunStation(ctx context.Context, station Station, feeders []Feeder, publishe
 	}
 }
 
-func stationTokenId(stationMacAddress string) string {
-	return Sha1(strings.ToUpper(stationMacAddress))
-}
-
-func correctPm(m *api.Measurement) {
-	if m.Humidity == nil {
-		return
-	}
-
-	if m.Pm25 != nil {
-		*m.Pm25 = Float32Round(correctedPm(*m.Pm25, *m.Humidity, 0.48756, 8.60068), 1)
-	}
-
-	if m.Pm10 != nil {
-		*m.Pm10 = Float32Round(correctedPm(*m.Pm10, *m.Humidity, 0.81559, 5.83411), 1)
+// pinState returns GPIO pin state for heater state
+func pinState(state HeaterState) int {
+	if state == HeaterOn {
+		return 1
 	}
+	return 0
 }
 
-func correctedPm(pm, humidity float32, a, b float64) float32 {
-	return float32(float64(pm) / (1.0 + a*math.Pow(float64(humidity)/100.0, b)))
+func stationTokenId(stationMacAddress string) string {
+	return Sha1(strings.ToUpper(stationMacAddress))
 }
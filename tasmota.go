@@ -0,0 +1,310 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/openairtech/api"
+)
+
+const (
+	// Millimeters of mercury to hectopascals
+	mmHgToHpa = 1.33322
+)
+
+// TasmotaStatusSns is the Tasmota `Status 10` command response
+type TasmotaStatusSns struct {
+	StatusSNS map[string]json.RawMessage `json:"StatusSNS"`
+}
+
+// TasmotaStatusSts is the Tasmota `Status 11` command response
+type TasmotaStatusSts struct {
+	StatusSTS struct {
+		Uptime    string `json:"Uptime"`
+		UptimeSec *int64 `json:"UptimeSec"`
+		Wifi      struct {
+			Signal *int `json:"Signal"`
+		} `json:"Wifi"`
+	} `json:"StatusSTS"`
+}
+
+// TasmotaStatusNet is the Tasmota `Status 5` command response
+type TasmotaStatusNet struct {
+	StatusNET struct {
+		Mac string `json:"Mac"`
+	} `json:"StatusNET"`
+}
+
+// TasmotaStatusFwr is the Tasmota `Status 2` command response
+type TasmotaStatusFwr struct {
+	StatusFWR struct {
+		Version string `json:"Version"`
+	} `json:"StatusFWR"`
+}
+
+// Tasmota uptime format: [days]T[hours]:[minutes]:[seconds]
+var tasmotaUptimeRe = regexp.MustCompile(`^(\d+)T(\d{1,2}):(\d{1,2}):(\d{1,2})$`)
+
+// ParseTasmotaUptime parses Tasmota uptime string like `1T02:03:04`
+func ParseTasmotaUptime(s string) (time.Duration, error) {
+	p := tasmotaUptimeRe.FindStringSubmatch(strings.TrimSpace(s))
+	if p == nil {
+		return 0, fmt.Errorf("invalid Tasmota uptime: %q", s)
+	}
+	var v [4]int64
+	for i := range v {
+		n, err := strconv.ParseInt(p[i+1], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		v[i] = n
+	}
+	return time.Duration(v[0])*24*time.Hour + time.Duration(v[1])*time.Hour +
+		time.Duration(v[2])*time.Minute + time.Duration(v[3])*time.Second, nil
+}
+
+// DefaultTasmotaMapping returns mapping of common Tasmota sensor names
+// (as reported in `StatusSNS` object) to the measurement fields
+func DefaultTasmotaMapping() map[string]SensorMapping {
+	return map[string]SensorMapping{
+		"BME280":  {Preset: "bme280"},
+		"BMP280":  {Preset: "bmp280"},
+		"BME680":  {Preset: "bme680"},
+		"SHT3X":   {Preset: "sht3x"},
+		"AM2301":  {Preset: "dht"},
+		"DHT11":   {Preset: "dht"},
+		"SI7021":  {Preset: "dht"},
+		"SDS0X1":  {Preset: "sds011"},
+		"PMS5003": {Preset: "pmsx003"},
+		"PMS7003": {Preset: "pmsx003"},
+	}
+}
+
+// Measurement creates measurement from Tasmota sensors values using given sensor mapping
+func (ts *TasmotaStatusSns) Measurement(t api.UnixTime, mapping map[string]SensorMapping) *api.Measurement {
+	m := api.Measurement{
+		Timestamp: &t,
+	}
+
+	var tempUnit, pressureUnit string
+	_ = json.Unmarshal(ts.StatusSNS["TempUnit"], &tempUnit)
+	_ = json.Unmarshal(ts.StatusSNS["PressureUnit"], &pressureUnit)
+
+	for name, raw := range ts.StatusSNS {
+		sm := findSensorMapping(mapping, name)
+		if sm == nil {
+			continue
+		}
+		var values map[string]interface{}
+		if err := json.Unmarshal(raw, &values); err != nil {
+			log.Debugf("can't parse Tasmota sensor %s values: %v", name, err)
+			continue
+		}
+		for n, v := range values {
+			fv, ok := v.(float64)
+			if !ok {
+				continue
+			}
+			vm := sm.Value(n)
+			if vm == nil {
+				continue
+			}
+			v := float32(fv)
+			switch {
+			case vm.Field == FieldTemperature && strings.EqualFold(tempUnit, "F"):
+				v = (v - 32) * 5 / 9
+			case vm.Field == FieldPressure && strings.EqualFold(pressureUnit, "mmHg"):
+				v *= mmHgToHpa
+			}
+			_ = SetMeasurementField(&m, vm.Field, vm.Apply(v))
+		}
+	}
+
+	return &m
+}
+
+// TasmotaStation reads sensors and controls heater relay
+// using Tasmota device HTTP command API
+type TasmotaStation struct {
+	version string
+
+	host string
+	port int
+
+	tokenId string
+
+	mapping map[string]SensorMapping
+
+	heaterRelay int
+	heaterState HeaterState
+
+	macAddress string
+	firmware   string
+	lastUptime *time.Duration
+}
+
+func NewTasmotaStation(version, host string, port int, heaterRelay int, tokenId string) *TasmotaStation {
+	return &TasmotaStation{
+		version:     version,
+		host:        host,
+		port:        port,
+		tokenId:     tokenId,
+		mapping:     DefaultTasmotaMapping(),
+		heaterRelay: heaterRelay,
+	}
+}
+
+func (ts *TasmotaStation) Version() string {
+	return ts.version
+}
+
+func (ts *TasmotaStation) Start() error {
+	log.Print("started Tasmota station")
+	return nil
+}
+
+func (ts *TasmotaStation) Stop() {
+	log.Print("stopped Tasmota station")
+}
+
+func (ts *TasmotaStation) HeaterState() HeaterState {
+	return ts.heaterState
+}
+
+func (ts *TasmotaStation) TurnHeater(state HeaterState) {
+	power := "Off"
+	if state == HeaterOn {
+		power = "On"
+	}
+
+	var response map[string]string
+	if err := HttpGetData(ts.commandUrl(fmt.Sprintf("Power%d %s", ts.heaterRelay, power)), &response); err != nil {
+		log.Errorf("can't turn heater relay %d %s: %v", ts.heaterRelay, power, err)
+		return
+	}
+
+	// Single relay devices report state as `POWER`, multiple relay ones as `POWER<n>`
+	rs, ok := response[fmt.Sprintf("POWER%d", ts.heaterRelay)]
+	if !ok {
+		rs, ok = response["POWER"]
+	}
+	if !ok || !strings.EqualFold(rs, power) {
+		log.Errorf("can't turn heater relay %d %s: unexpected response: %v", ts.heaterRelay, power, response)
+		return
+	}
+
+	ts.heaterState = state
+
+	if state == HeaterOn {
+		log.Debug("heater turned on")
+	} else {
+		log.Debug("heater turned off")
+	}
+}
+
+func (ts *TasmotaStation) GetData() (*StationData, error) {
+	log.Debugf("getting sensor data from Tasmota station %s:%d", ts.host, ts.port)
+
+	var sns TasmotaStatusSns
+	if err := HttpGetData(ts.commandUrl("Status 10"), &sns); err != nil {
+		log.Errorf("sensor data request failed: %v", err)
+		return nil, err
+	}
+	if sns.StatusSNS == nil {
+		return nil, errors.New("no sensor status in Tasmota station data")
+	}
+
+	log.Debugf("received sensor data: %+v", sns)
+
+	m := sns.Measurement(api.UnixTime(time.Now()), ts.mapping)
+
+	if ts.macAddress == "" {
+		var net TasmotaStatusNet
+		if err := HttpGetData(ts.commandUrl("Status 5"), &net); err != nil {
+			log.Errorf("network status request failed: %v", err)
+		} else {
+			ts.macAddress = net.StatusNET.Mac
+			log.Debugf("MAC address: %s", ts.macAddress)
+		}
+	}
+
+	tokenId := ts.tokenId
+	if tokenId == "" {
+		if ts.macAddress == "" {
+			return nil, errors.New("can't determine Tasmota station MAC address")
+		}
+		tokenId = stationTokenId(ts.macAddress)
+	}
+	log.Debugf("token ID: %s", tokenId)
+
+	if ts.firmware == "" {
+		var fwr TasmotaStatusFwr
+		if err := HttpGetData(ts.commandUrl("Status 2"), &fwr); err != nil {
+			log.Errorf("firmware status request failed: %v", err)
+		} else if fwr.StatusFWR.Version != "" {
+			ts.firmware = fwr.StatusFWR.Version
+			log.Infof("Tasmota station firmware: %s", ts.firmware)
+		}
+	}
+
+	var sts TasmotaStatusSts
+	if err := HttpGetData(ts.commandUrl("Status 11"), &sts); err != nil {
+		log.Errorf("status request failed: %v", err)
+		return nil, err
+	}
+
+	var uptime time.Duration
+	if sts.StatusSTS.UptimeSec != nil {
+		uptime = time.Duration(*sts.StatusSTS.UptimeSec) * time.Second
+	} else {
+		var err error
+		if uptime, err = ParseTasmotaUptime(sts.StatusSTS.Uptime); err != nil {
+			return nil, err
+		}
+	}
+
+	if ts.lastUptime != nil && uptime < *ts.lastUptime {
+		log.Warn("Tasmota station reboot detected")
+		ts.heaterState = HeaterOff
+	}
+
+	ts.lastUptime = &uptime
+
+	return &StationData{
+		Version:         ts.version,
+		TokenId:         tokenId,
+		MacAddress:      ts.macAddress,
+		Uptime:          uptime,
+		LastMeasurement: m,
+		Device: &DeviceStatus{
+			Firmware: ts.firmware,
+			Rssi:     sts.StatusSTS.Wifi.Signal,
+		},
+	}, nil
+}
+
+func (ts *TasmotaStation) commandUrl(cmnd string) string {
+	return fmt.Sprintf("http://%s:%d/cm?cmnd=%s", ts.host, ts.port, url.PathEscape(cmnd))
+}
@@ -0,0 +1,72 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/openairtech/api"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTasmotaUptime(t *testing.T) {
+	tests := []struct {
+		uptime  string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"0T00:00:15", 15 * time.Second, false},
+		{"1T02:03:04", 26*time.Hour + 3*time.Minute + 4*time.Second, false},
+		{"123T23:59:59", 123*24*time.Hour + 23*time.Hour + 59*time.Minute + 59*time.Second, false},
+		{"02:03:04", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.uptime, func(t *testing.T) {
+			got, err := ParseTasmotaUptime(tt.uptime)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTasmotaStatusSns_Measurement(t *testing.T) {
+	b, err := ioutil.ReadFile(filepath.Join("testdata", "tasmota-status10.json"))
+	require.NoError(t, err)
+	var sns TasmotaStatusSns
+	require.NoError(t, json.Unmarshal(b, &sns))
+
+	m := sns.Measurement(api.UnixTime(time.Now()), DefaultTasmotaMapping())
+
+	require.NotNil(t, m.Temperature)
+	require.InDelta(t, 22.0, *m.Temperature, 0.01)
+	require.NotNil(t, m.Humidity)
+	require.Equal(t, float32(41.2), *m.Humidity)
+	require.NotNil(t, m.Pressure)
+	require.InDelta(t, 1015.1, *m.Pressure, 0.1)
+	require.NotNil(t, m.Pm25)
+	require.Equal(t, float32(3.4), *m.Pm25)
+	require.NotNil(t, m.Pm10)
+	require.Equal(t, float32(7.9), *m.Pm10)
+}
@@ -20,6 +20,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"math"
 	"net"
 	"os/exec"
@@ -58,6 +59,28 @@ func WirelessInterfaceMacAddr() string {
 	return ""
 }
 
+// InterfaceMacAddr gets network interface with given name MAC address
+// or empty string if there is no such interface or it has no MAC address
+func InterfaceMacAddr(name string) string {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return ""
+	}
+	return iface.HardwareAddr.String()
+}
+
+// MachineId gets systemd machine ID (see machine-id(5)) or empty string if it is not available
+func MachineId() string {
+	for _, fn := range []string{"/etc/machine-id", "/var/lib/dbus/machine-id"} {
+		if b, err := ioutil.ReadFile(fn); err == nil {
+			if id := strings.TrimSpace(string(b)); id != "" {
+				return id
+			}
+		}
+	}
+	return ""
+}
+
 // Sha1 computes SHA1 checksum for given string
 func Sha1(s string) string {
 	h := sha1.New()
@@ -80,14 +103,6 @@ func Float32Round(x float32, places int) float32 {
 	return float32(math.Round(pow*float64(x)) / pow)
 }
 
-// Float32RefRound rounds referenced float32 to the given number of decimal places
-func Float32RefRound(r *float32, places int) float32 {
-	if r == nil {
-		return 0
-	}
-	return Float32Round(*r, places)
-}
-
 // SliceToString convert string slice s to the comma-separated values string
 func SliceToString(s []string) string {
 	return strings.Join(s, ", ")
@@ -103,6 +118,78 @@ func StringInSlice(a string, s []string) bool {
 	return false
 }
 
+// ParseKeyValueList parses comma-separated list of key=value pairs to map
+func ParseKeyValueList(s string) (map[string]string, error) {
+	kv := make(map[string]string)
+	for _, e := range strings.Split(s, ",") {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		p := strings.SplitN(e, "=", 2)
+		if len(p) != 2 || strings.TrimSpace(p[0]) == "" {
+			return nil, fmt.Errorf("invalid key=value pair: %q", e)
+		}
+		kv[strings.TrimSpace(p[0])] = strings.TrimSpace(p[1])
+	}
+	return kv, nil
+}
+
+// TimeRange is the time of day range, range end could be before its start
+// for ranges crossing midnight (like 22:00-06:00)
+type TimeRange struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// ParseTimeRange parses time of day range in HH:MM-HH:MM format
+func ParseTimeRange(s string) (TimeRange, error) {
+	var tr TimeRange
+	p := strings.SplitN(strings.TrimSpace(s), "-", 2)
+	if len(p) != 2 {
+		return tr, fmt.Errorf("invalid time range: %q", s)
+	}
+	for i, d := range []*time.Duration{&tr.Start, &tr.End} {
+		t, err := time.Parse("15:04", strings.TrimSpace(p[i]))
+		if err != nil {
+			return tr, fmt.Errorf("invalid time range: %q", s)
+		}
+		*d = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	}
+	return tr, nil
+}
+
+// ParseTimeRanges parses comma-separated list of time of day ranges
+func ParseTimeRanges(s string) ([]TimeRange, error) {
+	var trs []TimeRange
+	for _, e := range strings.Split(s, ",") {
+		if strings.TrimSpace(e) == "" {
+			continue
+		}
+		tr, err := ParseTimeRange(e)
+		if err != nil {
+			return nil, err
+		}
+		trs = append(trs, tr)
+	}
+	return trs, nil
+}
+
+// Contains checks given time of day (in time location) is in the range
+func (tr TimeRange) Contains(t time.Time) bool {
+	d := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second
+	if tr.Start <= tr.End {
+		return d >= tr.Start && d < tr.End
+	}
+	return d >= tr.Start || d < tr.End
+}
+
+func (tr TimeRange) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", int(tr.Start.Hours()), int(tr.Start.Minutes())%60,
+		int(tr.End.Hours()), int(tr.End.Minutes())%60)
+}
+
 // SubString extracts substring from input string start position with given length
 func SubString(input string, start int, length int) string {
 	asRunes := []rune(input)
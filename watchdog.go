@@ -0,0 +1,244 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Station connection states
+const (
+	StationStateOnline   = "online"
+	StationStateDegraded = "degraded"
+	StationStateOffline  = "offline"
+)
+
+func StationStateList() []string {
+	return []string{StationStateOnline, StationStateDegraded, StationStateOffline}
+}
+
+const (
+	// Power cycle command execution timeout
+	powerCycleCommandTimeout = time.Minute
+)
+
+// WatchdogStatus is the station connection watchdog state
+type WatchdogStatus struct {
+	State          string     `json:"state"`
+	Failures       int        `json:"failures"`
+	LastSuccess    *time.Time `json:"last_success,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	LastPowerCycle *time.Time `json:"last_power_cycle,omitempty"`
+}
+
+// PowerCycler turns station power off and on
+type PowerCycler interface {
+	PowerCycle() error
+}
+
+// CommandPowerCycler power cycles station by executing shell command
+type CommandPowerCycler struct {
+	command string
+}
+
+func NewCommandPowerCycler(command string) *CommandPowerCycler {
+	return &CommandPowerCycler{command: command}
+}
+
+func (cpc *CommandPowerCycler) PowerCycle() error {
+	return Execute(cpc.command, powerCycleCommandTimeout)
+}
+
+// GpioPowerCycler power cycles station by switching relay connected to GPIO line
+type GpioPowerCycler struct {
+	chipName string
+	lineId   int
+	offValue int
+	offTime  time.Duration
+
+	chip GpioChip
+	line GpioLine
+}
+
+// NewGpioPowerCycler creates power cycler setting given GPIO chip line to off value
+// (1 or 0 for active low relays) for given time to turn station power off
+func NewGpioPowerCycler(chipName string, lineId int, offValue int, offTime time.Duration) *GpioPowerCycler {
+	return &GpioPowerCycler{
+		chipName: chipName,
+		lineId:   lineId,
+		offValue: offValue,
+		offTime:  offTime,
+	}
+}
+
+func (gpc *GpioPowerCycler) PowerCycle() error {
+	if gpc.line == nil {
+		chip, err := OpenGpioChip(gpc.chipName)
+		if err != nil {
+			return err
+		}
+		line, err := chip.OutputLine(gpc.lineId)
+		if err != nil {
+			CloseQuietly(chip)
+			return err
+		}
+		gpc.chip, gpc.line = chip, line
+	}
+	if err := gpc.line.SetValue(gpc.offValue); err != nil {
+		return err
+	}
+	time.Sleep(gpc.offTime)
+	return gpc.line.SetValue(1 - gpc.offValue)
+}
+
+// Close releases GPIO line
+func (gpc *GpioPowerCycler) Close() error {
+	if gpc.line == nil {
+		return nil
+	}
+	CloseQuietly(gpc.line)
+	return gpc.chip.Close()
+}
+
+// Watchdog tracks consecutive station data request failures, makes station name to be resolved again
+// when station becomes degraded and power cycles offline station
+type Watchdog struct {
+	sync.Mutex
+
+	degradedFailures   int
+	offlineFailures    int
+	host               string
+	powerCycler        PowerCycler
+	powerCycleInterval time.Duration
+
+	status WatchdogStatus
+	// Power cycle is in progress
+	powerCycling bool
+	powerCycles  sync.WaitGroup
+}
+
+// NewWatchdog creates watchdog treating station as degraded and offline after given numbers
+// of consecutive failures and power cycling offline station (if power cycler is not nil)
+// not more often than given interval
+func NewWatchdog(degradedFailures, offlineFailures int, host string, powerCycler PowerCycler,
+	powerCycleInterval time.Duration) *Watchdog {
+	w := &Watchdog{
+		degradedFailures:   degradedFailures,
+		offlineFailures:    offlineFailures,
+		host:               host,
+		powerCycler:        powerCycler,
+		powerCycleInterval: powerCycleInterval,
+		status:             WatchdogStatus{State: StationStateOnline},
+	}
+	w.updateMetrics()
+	return w
+}
+
+// Success registers successful station data request at given time
+func (w *Watchdog) Success(now time.Time) {
+	w.Lock()
+	defer w.Unlock()
+	if w.status.State != StationStateOnline {
+		log.Infof("station is online after %d failed data request(s)", w.status.Failures)
+	}
+	w.status.State = StationStateOnline
+	w.status.Failures = 0
+	w.status.LastSuccess = &now
+	w.status.LastError = ""
+	w.updateMetrics()
+}
+
+// Failure registers failed station data request at given time
+func (w *Watchdog) Failure(now time.Time, err error) {
+	w.Lock()
+	defer w.Unlock()
+	w.status.Failures++
+	w.status.LastError = TruncateString(err.Error(), maxFeederErrorLogLength)
+	metricStationFailures.Inc()
+
+	state := w.status.State
+	switch {
+	case w.status.Failures >= w.offlineFailures:
+		state = StationStateOffline
+	case w.status.Failures >= w.degradedFailures:
+		state = StationStateDegraded
+	}
+
+	if state != w.status.State {
+		w.status.State = state
+		w.updateMetrics()
+		if state == StationStateDegraded {
+			log.Warnf("station is degraded after %d failed data request(s): %v", w.status.Failures, err)
+			if w.host != "" {
+				ReresolveHost(w.host)
+			}
+		} else {
+			log.Errorf("station is offline after %d failed data request(s): %v", w.status.Failures, err)
+		}
+	} else if state == StationStateOffline {
+		// Don't flood log with errors of offline station
+		log.Debugf("station data request failed: %v", err)
+	} else {
+		log.Errorf("station data request failed: %v", err)
+	}
+
+	if state == StationStateOffline && w.powerCycler != nil && !w.powerCycling &&
+		(w.status.LastPowerCycle == nil || now.Sub(*w.status.LastPowerCycle) >= w.powerCycleInterval) {
+		w.status.LastPowerCycle = &now
+		w.powerCycling = true
+		w.powerCycles.Add(1)
+		go w.powerCycle()
+	}
+}
+
+// powerCycle power cycles offline station, it may take a while so it's done without holding the lock
+func (w *Watchdog) powerCycle() {
+	defer w.powerCycles.Done()
+	log.Warn("power cycling offline station")
+	metricStationPowerCycles.Inc()
+	if err := w.powerCycler.PowerCycle(); err != nil {
+		log.Errorf("can't power cycle station: %v", err)
+	}
+	w.Lock()
+	w.powerCycling = false
+	w.Unlock()
+}
+
+// Status returns watchdog state
+func (w *Watchdog) Status() *WatchdogStatus {
+	w.Lock()
+	defer w.Unlock()
+	s := w.status
+	return &s
+}
+
+// ServeHTTP serves watchdog state as JSON
+func (w *Watchdog) ServeHTTP(rw http.ResponseWriter, _ *http.Request) {
+	writeJson(rw, w.Status())
+}
+
+func (w *Watchdog) updateMetrics() {
+	for _, s := range StationStateList() {
+		v := 0.0
+		if s == w.status.State {
+			v = 1
+		}
+		metricStationState.Set(v, "state", s)
+	}
+}
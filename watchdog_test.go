@@ -0,0 +1,115 @@
+// Copyright © 2026 Victor Antonovich <victor@antonovich.me>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testPowerCycler struct {
+	sync.Mutex
+	cycles  int
+	release chan struct{}
+}
+
+func (tpc *testPowerCycler) PowerCycle() error {
+	if tpc.release != nil {
+		<-tpc.release
+	}
+	tpc.Lock()
+	defer tpc.Unlock()
+	tpc.cycles++
+	return nil
+}
+
+func (tpc *testPowerCycler) count() int {
+	tpc.Lock()
+	defer tpc.Unlock()
+	return tpc.cycles
+}
+
+func TestWatchdog(t *testing.T) {
+	tpc := &testPowerCycler{}
+	w := NewWatchdog(2, 3, "", tpc, 30*time.Minute)
+	require.Equal(t, StationStateOnline, w.Status().State)
+
+	now := time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC)
+	fail := func() {
+		now = now.Add(time.Minute)
+		w.Failure(now, errors.New("connection refused"))
+		w.powerCycles.Wait()
+	}
+
+	fail()
+	require.Equal(t, StationStateOnline, w.Status().State)
+	fail()
+	require.Equal(t, StationStateDegraded, w.Status().State)
+	require.Equal(t, 0, tpc.count())
+	fail()
+	s := w.Status()
+	require.Equal(t, StationStateOffline, s.State)
+	require.Equal(t, 3, s.Failures)
+	require.Equal(t, "connection refused", s.LastError)
+	require.Equal(t, 1, tpc.count())
+
+	// Power cycles are rate limited
+	for i := 0; i < 29; i++ {
+		fail()
+	}
+	require.Equal(t, 1, tpc.count())
+	fail()
+	require.Equal(t, 2, tpc.count())
+	require.Equal(t, now, *w.Status().LastPowerCycle)
+
+	w.Success(now)
+	s = w.Status()
+	require.Equal(t, StationStateOnline, s.State)
+	require.Equal(t, 0, s.Failures)
+	require.Empty(t, s.LastError)
+	require.Equal(t, now, *s.LastSuccess)
+
+	rec := httptest.NewRecorder()
+	w.ServeHTTP(rec, httptest.NewRequest("GET", "/watchdog", nil))
+	var ws WatchdogStatus
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &ws))
+	require.Equal(t, StationStateOnline, ws.State)
+}
+
+func TestWatchdog_SlowPowerCycle(t *testing.T) {
+	tpc := &testPowerCycler{release: make(chan struct{})}
+	w := NewWatchdog(1, 1, "", tpc, 0)
+	now := time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	// Failures are registered while power cycle is in progress without starting another one
+	w.Failure(now, errors.New("connection refused"))
+	w.Failure(now.Add(time.Minute), errors.New("connection refused"))
+	s := w.Status()
+	require.Equal(t, 2, s.Failures)
+	require.Equal(t, now, *s.LastPowerCycle)
+
+	close(tpc.release)
+	w.powerCycles.Wait()
+	require.Equal(t, 1, tpc.count())
+	w.Failure(now.Add(2*time.Minute), errors.New("connection refused"))
+	w.powerCycles.Wait()
+	require.Equal(t, 2, tpc.count())
+}
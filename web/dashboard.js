@@ -0,0 +1,339 @@
+(function () {
+  'use strict';
+
+  var HISTORY_PERIOD = '24h';
+  var HISTORY_MAX_AGE = 24 * 3600;
+  var LOG_REFRESH_INTERVAL = 10000;
+  var DATA_REFRESH_INTERVAL = 30000;
+
+  // US EPA PM2.5 AQI breakpoints: [concentration low, high, AQI low, high, category, color]
+  var AQI_BREAKPOINTS = [
+    [0.0, 9.0, 0, 50, 'Good', '#00e400'],
+    [9.1, 35.4, 51, 100, 'Moderate', '#ffff00'],
+    [35.5, 55.4, 101, 150, 'Unhealthy for sensitive groups', '#ff7e00'],
+    [55.5, 125.4, 151, 200, 'Unhealthy', '#ff0000'],
+    [125.5, 225.4, 201, 300, 'Very unhealthy', '#8f3f97'],
+    [225.5, 325.4, 301, 500, 'Hazardous', '#7e0023']
+  ];
+
+  var history = [];
+
+  function $(id) {
+    return document.getElementById(id);
+  }
+
+  function fmt(v, places) {
+    return v === undefined || v === null ? '–' : v.toFixed(places);
+  }
+
+  function fmtAverages(a) {
+    var s = [];
+    if (a && a.avg_1h !== undefined) {
+      s.push('1h ' + fmt(a.avg_1h, 1));
+    }
+    if (a && a.avg_24h !== undefined) {
+      s.push('24h ' + fmt(a.avg_24h, 1));
+    }
+    if (a && a.nowcast !== undefined) {
+      s.push('NowCast ' + fmt(a.nowcast, 1));
+    }
+    return s.length > 0 ? s.join(' · ') : ' ';
+  }
+
+  function fmtTime(t) {
+    return t ? new Date(t).toLocaleString() : '–';
+  }
+
+  function fmtUptime(s) {
+    var d = Math.floor(s / 86400), h = Math.floor(s % 86400 / 3600), m = Math.floor(s % 3600 / 60);
+    return (d > 0 ? d + 'd ' : '') + h + 'h ' + m + 'm';
+  }
+
+  function aqi(pm25) {
+    var c = Math.floor(pm25 * 10) / 10;
+    for (var i = 0; i < AQI_BREAKPOINTS.length; i++) {
+      var b = AQI_BREAKPOINTS[i];
+      if (c <= b[1] || i === AQI_BREAKPOINTS.length - 1) {
+        var v = Math.round((b[3] - b[2]) / (b[1] - b[0]) * (Math.min(c, b[1]) - b[0]) + b[2]);
+        return {value: v, category: b[4], color: b[5], dark: i >= 3};
+      }
+    }
+  }
+
+  function updateData(d) {
+    var m = d.measurement || {};
+    var station = [d.version, d.token_id.substring(0, 12), 'up ' + fmtUptime(d.uptime),
+      fmtTime(m.timestamp * 1000)];
+    var dev = d.device || {};
+    if (dev.firmware) {
+      station.push('firmware ' + dev.firmware);
+    }
+    if (dev.rssi !== undefined) {
+      station.push('RSSI ' + dev.rssi + ' dBm');
+    }
+    if (dev.free_ram !== undefined) {
+      station.push('free RAM ' + dev.free_ram);
+    }
+    if (dev.load !== undefined) {
+      station.push('load ' + dev.load.toFixed(1) + '%');
+    }
+    if (dev.reset_reason) {
+      station.push('reset: ' + dev.reset_reason);
+    }
+    $('station').textContent = station.join(' · ');
+    $('pm25').textContent = fmt(m.pm25, 1);
+    $('pm10').textContent = fmt(m.pm10, 1);
+    $('temperature').textContent = fmt(m.temperature, 1);
+    $('humidity').textContent = fmt(m.humidity, 1);
+    $('pressure').textContent = fmt(m.pressure, 1);
+    var av = d.averages || {};
+    $('pm25-avg').textContent = fmtAverages(av.pm25);
+    $('pm10-avg').textContent = fmtAverages(av.pm10);
+    $('heater').textContent = d.heater ? 'ON' : 'OFF';
+    var hc = d.heater_control;
+    $('heater-control').textContent = hc ? hc.strategy + ', duty ' + hc.duty_cycle.toFixed(0) + '%' : ' ';
+    $('heater-control').title = hc && hc.reason ? hc.reason : '';
+
+    var dv = d.derived || {};
+    var derived = [];
+    if (dv.dew_point !== undefined) {
+      derived.push('dew point ' + fmt(dv.dew_point, 1) + ' °C');
+    }
+    if (dv.absolute_humidity !== undefined) {
+      derived.push('absolute humidity ' + fmt(dv.absolute_humidity, 1) + ' g/m³');
+    }
+    if (dv.heat_index !== undefined) {
+      derived.push('heat index ' + fmt(dv.heat_index, 1) + ' °C');
+    }
+    if (dv.sea_level_pressure !== undefined) {
+      derived.push('sea-level pressure ' + fmt(dv.sea_level_pressure, 1) + ' hPa');
+    }
+    $('derived').textContent = derived.join(' · ');
+
+    var quality = $('quality');
+    quality.innerHTML = '';
+    (d.quality || []).forEach(function (q) {
+      var li = document.createElement('li');
+      li.textContent = q.message;
+      quality.appendChild(li);
+    });
+
+    var card = $('aqi-card');
+    if (m.pm25 !== undefined) {
+      var a = aqi(m.pm25);
+      $('aqi').textContent = a.value;
+      $('aqi-category').textContent = a.category;
+      card.style.background = a.color;
+      card.style.color = a.dark ? '#fff' : '#222';
+    } else {
+      $('aqi').textContent = '–';
+      $('aqi-category').textContent = ' ';
+      card.style.background = '';
+      card.style.color = '';
+    }
+
+    updateFeeders(d.feeders || []);
+
+    if (m.timestamp && (history.length === 0 || history[history.length - 1].timestamp < m.timestamp)) {
+      history.push(m);
+      var oldest = m.timestamp - HISTORY_MAX_AGE;
+      while (history.length > 0 && history[0].timestamp < oldest) {
+        history.shift();
+      }
+      drawCharts();
+    }
+  }
+
+  function updateFeeders(feeders) {
+    var tbody = $('feeders');
+    tbody.innerHTML = '';
+    feeders.forEach(function (f) {
+      var tr = document.createElement('tr');
+      [f.name, fmtTime(f.last_success), fmtTime(f.last_failure), f.last_error || ''].forEach(function (v, i) {
+        var td = document.createElement('td');
+        td.textContent = v;
+        if (i === 3 && f.last_failure && (!f.last_success || f.last_failure > f.last_success)) {
+          td.className = 'error';
+        }
+        tr.appendChild(td);
+      });
+      tbody.appendChild(tr);
+    });
+  }
+
+  function drawChart(canvas, series) {
+    var ratio = window.devicePixelRatio || 1;
+    var w = canvas.clientWidth, h = canvas.clientHeight;
+    canvas.width = w * ratio;
+    canvas.height = h * ratio;
+    var ctx = canvas.getContext('2d');
+    ctx.scale(ratio, ratio);
+    ctx.clearRect(0, 0, w, h);
+    ctx.font = '11px sans-serif';
+
+    var pad = {left: 44, right: 44, top: 20, bottom: 22};
+    var pw = w - pad.left - pad.right, ph = h - pad.top - pad.bottom;
+
+    if (history.length < 2) {
+      ctx.fillStyle = '#888';
+      ctx.fillText('no history data', pad.left, pad.top + ph / 2);
+      return;
+    }
+
+    var t0 = history[0].timestamp, t1 = history[history.length - 1].timestamp;
+    function x(t) {
+      return pad.left + (t - t0) / (t1 - t0) * pw;
+    }
+
+    // Time axis
+    ctx.strokeStyle = '#ddd';
+    ctx.fillStyle = '#666';
+    ctx.beginPath();
+    for (var i = 0; i <= 6; i++) {
+      var t = t0 + (t1 - t0) * i / 6;
+      ctx.moveTo(x(t), pad.top);
+      ctx.lineTo(x(t), pad.top + ph);
+      var d = new Date(t * 1000);
+      var label = ('0' + d.getHours()).slice(-2) + ':' + ('0' + d.getMinutes()).slice(-2);
+      ctx.fillText(label, x(t) - 14, h - 6);
+    }
+    ctx.stroke();
+
+    series.forEach(function (s, si) {
+      var values = history.map(function (m) {
+        return m[s.key];
+      }).filter(function (v) {
+        return v !== undefined && v !== null;
+      });
+      if (values.length === 0) {
+        return;
+      }
+      var min = s.axis === 'shared' ? series.sharedMin : Math.min.apply(null, values);
+      var max = s.axis === 'shared' ? series.sharedMax : Math.max.apply(null, values);
+      if (max === min) {
+        max = min + 1;
+      }
+      function y(v) {
+        return pad.top + ph - (v - min) / (max - min) * ph;
+      }
+
+      ctx.strokeStyle = s.color;
+      ctx.lineWidth = 1.5;
+      ctx.beginPath();
+      var drawing = false;
+      history.forEach(function (m) {
+        var v = m[s.key];
+        if (v === undefined || v === null) {
+          drawing = false;
+          return;
+        }
+        if (drawing) {
+          ctx.lineTo(x(m.timestamp), y(v));
+        } else {
+          ctx.moveTo(x(m.timestamp), y(v));
+          drawing = true;
+        }
+      });
+      ctx.stroke();
+
+      // Value axis labels on the left for the first series and on the right for the second one
+      if (s.axis !== 'shared' || si === 0) {
+        ctx.fillStyle = s.axis === 'shared' ? '#666' : s.color;
+        var ax = si === 0 ? 4 : w - pad.right + 4;
+        ctx.fillText(max.toFixed(1), ax, pad.top + 4);
+        ctx.fillText(min.toFixed(1), ax, pad.top + ph);
+      }
+      ctx.fillStyle = s.color;
+      ctx.fillText(s.label, pad.left + 8 + si * 110, 12);
+    });
+  }
+
+  function drawCharts() {
+    var pm = [
+      {key: 'pm25', label: 'PM2.5, µg/m³', color: '#e67e22', axis: 'shared'},
+      {key: 'pm10', label: 'PM10, µg/m³', color: '#8e44ad', axis: 'shared'}
+    ];
+    var pmValues = [];
+    history.forEach(function (m) {
+      [m.pm25, m.pm10].forEach(function (v) {
+        if (v !== undefined && v !== null) {
+          pmValues.push(v);
+        }
+      });
+    });
+    pm.sharedMin = 0;
+    pm.sharedMax = pmValues.length > 0 ? Math.max.apply(null, pmValues) : 1;
+    drawChart($('pm-chart'), pm);
+    drawChart($('env-chart'), [
+      {key: 'temperature', label: 'Temperature, °C', color: '#c0392b'},
+      {key: 'humidity', label: 'Humidity, %', color: '#2980b9'}
+    ]);
+  }
+
+  function getJson(url, cb) {
+    var xhr = new XMLHttpRequest();
+    xhr.open('GET', url);
+    xhr.onload = function () {
+      if (xhr.status === 200) {
+        cb(JSON.parse(xhr.responseText));
+      } else if (url.indexOf('/api/history') === 0) {
+        $('history-note').textContent = '(measurements history is disabled, showing live data only)';
+      }
+    };
+    xhr.send();
+  }
+
+  function refreshLog() {
+    getJson('/api/log', function (entries) {
+      var pre = $('log');
+      var atBottom = pre.scrollTop + pre.clientHeight >= pre.scrollHeight - 4;
+      pre.innerHTML = '';
+      entries.forEach(function (e) {
+        var span = document.createElement('span');
+        span.className = e.level;
+        span.textContent = new Date(e.time).toLocaleTimeString() + ' ' +
+          e.level.toUpperCase().substring(0, 4) + ' ' + e.message + '\n';
+        pre.appendChild(span);
+      });
+      if (atBottom) {
+        pre.scrollTop = pre.scrollHeight;
+      }
+    });
+  }
+
+  function refreshWatchdog() {
+    getJson('/watchdog', function (w) {
+      var el = $('watchdog');
+      el.className = 'watchdog ' + w.state;
+      el.textContent = w.state === 'online' ? '' : 'station is ' + w.state + ' after ' + w.failures +
+        ' failed data request(s)' + (w.last_error ? ': ' + w.last_error : '');
+    });
+  }
+
+  function connectEvents() {
+    if (!window.EventSource) {
+      setInterval(function () {
+        getJson('/api/data', updateData);
+      }, DATA_REFRESH_INTERVAL);
+      return;
+    }
+    var es = new EventSource('/events');
+    es.addEventListener('data', function (e) {
+      updateData(JSON.parse(e.data));
+    });
+  }
+
+  getJson('/api/history?period=' + HISTORY_PERIOD, function (ms) {
+    history = ms.concat(history.filter(function (m) {
+      return ms.length === 0 || m.timestamp > ms[ms.length - 1].timestamp;
+    }));
+    drawCharts();
+  });
+  getJson('/api/data', updateData);
+  connectEvents();
+  refreshLog();
+  setInterval(refreshLog, LOG_REFRESH_INTERVAL);
+  refreshWatchdog();
+  setInterval(refreshWatchdog, LOG_REFRESH_INTERVAL);
+  window.addEventListener('resize', drawCharts);
+})();